@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Entry is single record of audit log.
+type Entry struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+
+	UserID       string `json:"user_id,omitempty"`
+	AuthDataType string `json:"auth_data_type,omitempty"`
+	ProviderName string `json:"provider_name,omitempty"`
+	RemoteAddr   string `json:"remote_addr,omitempty"`
+	Error        string `json:"error,omitempty"`
+
+	Data json.RawMessage `json:"data,omitempty"`
+
+	// PrevHash is hash of previous line. It's empty for the first one.
+	PrevHash string `json:"prev_hash"`
+}
+
+// line is what's actually written to log file.
+// Hash covers previous hash and exact bytes of entry, so nothing has to be re-encoded during verification.
+type line struct {
+	Entry json.RawMessage `json:"entry"`
+	Hash  string          `json:"hash"`
+}
+
+func computeHash(prevHash string, rawEntry []byte) string {
+	h := sha256.New()
+	_, _ = h.Write([]byte(prevHash))
+	_, _ = h.Write([]byte{'\n'})
+	_, _ = h.Write(rawEntry)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// permissionData is how perm.CheckResult is stored. User and Subject are skipped, since they may be
+// arbitrary values, which can't be encoded.
+type permissionData struct {
+	Permission   string            `json:"permission"`
+	Allowed      bool              `json:"allowed"`
+	VoterResults map[string]string `json:"voter_results,omitempty"`
+}
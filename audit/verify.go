@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// maxLineSize is max size of single line, including newline. Writer refuses larger entries, so anything it writes can be verified.
+const maxLineSize = 1024 * 1024
+
+// ErrEntryTooLarge is returned by Writer, when encoded entry does not fit in max line size, which is 1 MiB.
+var ErrEntryTooLarge = errors.New("rocho/audit: Entry is too large")
+
+// ErrHashMismatch is returned when hash of line does not match it's content.
+var ErrHashMismatch = errors.New("rocho/audit: Line hash mismatch")
+
+// ErrChainBroken is returned when line does not point to hash of previous line.
+var ErrChainBroken = errors.New("rocho/audit: Line does not point to previous one")
+
+// ErrSequenceBroken is returned when sequence numbers of lines are not consecutive.
+var ErrSequenceBroken = errors.New("rocho/audit: Sequence number is not consecutive")
+
+// BrokenLinkError is returned by Verify for first line, which breaks chain.
+type BrokenLinkError struct {
+	Line int // 1-based
+	Err  error
+}
+
+func (err *BrokenLinkError) Error() string {
+	if err == nil {
+		return "<nil>"
+	}
+	if err.Err == nil {
+		return fmt.Sprintf("rocho/audit: Broken link at line %d", err.Line)
+	}
+	return fmt.Sprintf("rocho/audit: Broken link at line %d: %s", err.Line, err.Err.Error())
+}
+func (err *BrokenLinkError) Unwrap() error {
+	if err == nil {
+		return nil
+	}
+	return err.Err
+}
+
+// Verify walks audit log file and checks it's hash chain.
+// It returns *BrokenLinkError pointing to first broken line, if there is any.
+//
+// Note: Truncation of log's tail can't be detected this way. Store last hash somewhere else to detect it.
+func Verify(path string) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	_, _, _, err = verifyChain(f)
+	return
+}
+
+// verifyChain checks hash chain read from r and returns sequence number and hash of it's last line.
+// Terminated is false if last line does not end with newline.
+func verifyChain(r io.Reader) (seq uint64, prevHash string, terminated bool, err error) {
+	terminated = true
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		advance, token, err = bufio.ScanLines(data, atEOF)
+		if atEOF && token != nil && advance == len(data) && data[len(data)-1] != '\n' {
+			terminated = false
+		}
+		return
+	})
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+
+		var l line
+		err = json.Unmarshal(scanner.Bytes(), &l)
+		if err != nil {
+			err = &BrokenLinkError{Line: lineNo, Err: err}
+			return
+		}
+		if computeHash(prevHash, l.Entry) != l.Hash {
+			err = &BrokenLinkError{Line: lineNo, Err: ErrHashMismatch}
+			return
+		}
+
+		var e Entry
+		err = json.Unmarshal(l.Entry, &e)
+		if err != nil {
+			err = &BrokenLinkError{Line: lineNo, Err: err}
+			return
+		}
+		if e.PrevHash != prevHash {
+			err = &BrokenLinkError{Line: lineNo, Err: ErrChainBroken}
+			return
+		}
+		if e.Seq != seq+1 {
+			err = &BrokenLinkError{Line: lineNo, Err: ErrSequenceBroken}
+			return
+		}
+
+		prevHash = l.Hash
+		seq = e.Seq
+	}
+
+	err = scanner.Err()
+	return
+}
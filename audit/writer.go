@@ -0,0 +1,187 @@
+// Package audit implements append-only audit log of authentication events.
+//
+// Log is file of JSON lines. Each line contains hash of previous one, so modification or removal of any line
+// except the last ones can be detected with Verify.
+//
+// Note: Hash chain is not keyed. It detects accidental corruption and careless edits, but anyone who can write
+// to the log can rewrite it and recompute all following hashes. Ship log or it's last hash somewhere else
+// to protect it from such modification.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/teawithsand/rocho"
+	"github.com/teawithsand/rocho/perm"
+)
+
+// Writer implements rocho.EventListener, which appends events to audit log file.
+// If file already exists, chain is continued from it's last line.
+//
+// Existing chain is verified when file is opened. If it's broken, *BrokenLinkError is returned and nothing is written.
+// This includes last line torn by crash during write, which has to be inspected and removed manually.
+type Writer struct {
+	Path string
+	// Sync makes writer fsync file after each entry.
+	Sync bool
+
+	lock     sync.Mutex
+	file     *os.File
+	seq      uint64
+	prevHash string
+}
+
+// open must be called with lock held.
+func (w *Writer) open() (err error) {
+	if w.file != nil {
+		return
+	}
+
+	// verify existing chain, so broken or tampered log is never extended
+	terminated := true
+	f, err := os.Open(w.Path)
+	if err == nil {
+		var seq uint64
+		var prevHash string
+		seq, prevHash, terminated, err = verifyChain(f)
+		_ = f.Close()
+		if err != nil {
+			return
+		}
+		w.seq = seq
+		w.prevHash = prevHash
+	} else if !os.IsNotExist(err) {
+		return
+	}
+
+	w.file, err = os.OpenFile(w.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+
+	// last line is complete, but it's newline was not written
+	if !terminated {
+		_, err = w.file.Write([]byte{'\n'})
+		if err != nil {
+			_ = w.file.Close()
+			w.file = nil
+		}
+	}
+	return
+}
+
+// HandleEvent appends event to log.
+func (w *Writer) HandleEvent(ctx context.Context, e rocho.Event) (err error) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	entry := Entry{
+		Time:         e.Time.UTC(),
+		Type:         string(e.Type),
+		UserID:       e.UserID,
+		AuthDataType: e.AuthDataType,
+		ProviderName: e.ProviderName,
+		RemoteAddr:   e.RemoteAddr,
+	}
+	if e.Err != nil {
+		entry.Error = e.Err.Error()
+	}
+	entry.Data = encodeData(e.Data)
+
+	err = w.Append(entry)
+	return
+}
+
+// Append appends entry to log. Seq and PrevHash are overwritten.
+// Returns ErrEntryTooLarge if encoded entry is larger than 1 MiB, in which case nothing is written.
+func (w *Writer) Append(entry Entry) (err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	err = w.open()
+	if err != nil {
+		return
+	}
+
+	entry.Seq = w.seq + 1
+	entry.PrevHash = w.prevHash
+
+	rawEntry, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	l := line{
+		Entry: rawEntry,
+		Hash:  computeHash(w.prevHash, rawEntry),
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		return
+	}
+	if len(data)+1 > maxLineSize {
+		err = ErrEntryTooLarge
+		return
+	}
+
+	_, err = w.file.Write(append(data, '\n'))
+	if err != nil {
+		return
+	}
+	if w.Sync {
+		err = w.file.Sync()
+		if err != nil {
+			return
+		}
+	}
+
+	w.seq = entry.Seq
+	w.prevHash = l.Hash
+	return
+}
+
+// Close closes underlying file. Writer can be used again after that.
+func (w *Writer) Close() (err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return
+	}
+	err = w.file.Close()
+	w.file = nil
+	return
+}
+
+func encodeData(data interface{}) json.RawMessage {
+	switch t := data.(type) {
+	case nil:
+		return nil
+	case perm.CheckResult:
+		data = encodeCheckResult(&t)
+	case *perm.CheckResult:
+		data = encodeCheckResult(t)
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+func encodeCheckResult(cr *perm.CheckResult) (pd permissionData) {
+	pd.Permission = string(cr.Permission)
+	pd.Allowed = cr.Allow()
+	if len(cr.VoterResults) > 0 {
+		pd.VoterResults = map[string]string{}
+		for name, vr := range cr.VoterResults {
+			pd.VoterResults[name] = vr.String()
+		}
+	}
+	return
+}
@@ -0,0 +1,167 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func tempLog(t *testing.T) (path string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "rocho-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.Join(dir, "audit.log")
+	cleanup = func() {
+		_ = os.RemoveAll(dir)
+	}
+	return
+}
+
+func appendEntries(t *testing.T, path string, types ...string) {
+	t.Helper()
+
+	w := &Writer{Path: path}
+	defer w.Close()
+	for _, et := range types {
+		err := w.Append(Entry{Type: et})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWriter_ResumesChain(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	appendEntries(t, path, "login", "logout")
+	appendEntries(t, path, "login")
+
+	err := Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_RefusesBrokenChain(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	appendEntries(t, path, "login", "logout", "login")
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte(`"logout"`), []byte(`"logins"`), 1)
+	err = ioutil.WriteFile(path, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Writer{Path: path}
+	err = w.Append(Entry{Type: "login"})
+	var blerr *BrokenLinkError
+	if !errors.As(err, &blerr) || blerr.Line != 2 || !errors.Is(err, ErrHashMismatch) {
+		t.Fatalf("expected hash mismatch at line 2, got %v", err)
+	}
+
+	after, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after, data) {
+		t.Error("expected broken log not to be extended")
+	}
+}
+
+func TestWriter_TornLastLine(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	appendEntries(t, path, "login", "logout")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("complete line without newline", func(t *testing.T) {
+		err := ioutil.WriteFile(path, data[:len(data)-1], 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		appendEntries(t, path, "login")
+		err = Verify(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("partial line", func(t *testing.T) {
+		err := ioutil.WriteFile(path, data[:len(data)-10], 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := &Writer{Path: path}
+		err = w.Append(Entry{Type: "login"})
+		var blerr *BrokenLinkError
+		if !errors.As(err, &blerr) || blerr.Line != 2 {
+			t.Fatalf("expected broken link at line 2, got %v", err)
+		}
+	})
+}
+
+func TestWriter_EntrySize(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	appendError := func(path string, size int) (err error) {
+		w := &Writer{Path: path}
+		defer w.Close()
+		err = w.Append(Entry{Time: now, Type: "login", Error: strings.Repeat("a", size)})
+		return
+	}
+
+	// line with single character error tells how much space rest of entry takes
+	err := appendError(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxErrorSize := maxLineSize - len(data) + 1
+
+	for name, tc := range map[string]struct {
+		size int
+		err  error
+	}{
+		"at limit":   {size: maxErrorSize},
+		"over limit": {size: maxErrorSize + 1, err: ErrEntryTooLarge},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(filepath.Dir(path), strings.Replace(name, " ", "-", -1)+".log")
+			err := appendError(path, tc.size)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+
+			// entry which is too large is not written, so log can still be extended and verified
+			appendEntries(t, path, "logout")
+			err = Verify(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package rocho
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// responseWriter tracks if anything was written to response.
+type responseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	rw.written = true
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *responseWriter) Write(data []byte) (int, error) {
+	rw.written = true
+	return rw.ResponseWriter.Write(data)
+}
+
+// RefreshTokenIssuer issues refresh token for access token created during login.
+// It's implemented by refresh.Manager.
+type RefreshTokenIssuer interface {
+	Issue(ctx context.Context, at AuthToken) (refreshToken []byte, err error)
+}
+
+// TokenResponse is JSON body containing access token and optionally refresh token.
+// It follows RFC 6749 section 5.1.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// WriteTokenResponse writes TokenResponse for given access token as JSON body.
+// Expiration is taken from AuthToken if it implements ExpiringAuthToken.
+func WriteTokenResponse(w http.ResponseWriter, at AuthToken, accessToken, refreshToken []byte, now time.Time) (err error) {
+	res := TokenResponse{
+		AccessToken:  string(accessToken),
+		TokenType:    "Bearer",
+		RefreshToken: string(refreshToken),
+	}
+	if eat, ok := at.(ExpiringAuthToken); ok {
+		expiresAt := eat.ExpiresAt()
+		if !expiresAt.IsZero() {
+			res.ExpiresIn = int64(expiresAt.Sub(now) / time.Second)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(res)
+	return
+}
+
+// LoginHandler authenticates request using AuthEngine and serializes resulting AuthToken to response.
+//
+// If serializer has not written response itself(like bearer transport does), browser is redirected to
+// SuccessRedirectURL or 204 response is written.
+//
+// If RefreshTokenIssuer is set, access token serialized with AuthEngine.SerializerAuthToken and refresh token
+// are written together as TokenResponse instead. SuccessHandler and SuccessRedirectURL are not used then.
+type LoginHandler struct {
+	AuthEngine AuthEngine
+	// RefreshTokenIssuer, if set, makes login return refresh token alongside access token.
+	RefreshTokenIssuer RefreshTokenIssuer
+
+	SuccessRedirectURL string
+	// SuccessHandler, if set, is called after AuthToken was serialized to response instead of default behaviour.
+	SuccessHandler func(w http.ResponseWriter, r *http.Request, at AuthToken)
+	// ErrorHandler is called when authentication fails. If nil, DefaultErrorRenderer is used.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+func (handler *LoginHandler) now() time.Time {
+	if handler.Now != nil {
+		return handler.Now()
+	}
+	return time.Now()
+}
+
+func (handler *LoginHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if handler.ErrorHandler != nil {
+		handler.ErrorHandler(w, r, err)
+		return
+	}
+	defaultErrorRenderer.RenderError(w, r, err)
+}
+
+func (handler *LoginHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	at, err := handler.AuthEngine.AuthenticateRequest(ctx, r)
+	if err != nil {
+		handler.handleError(w, r, err)
+		return
+	}
+
+	if handler.RefreshTokenIssuer != nil {
+		handler.writeTokens(w, r, at)
+		return
+	}
+
+	rw := &responseWriter{ResponseWriter: w}
+	err = handler.AuthEngine.SerializeAuthTokenToResponse(ctx, at, rw)
+	if err != nil {
+		if !rw.written {
+			handler.handleError(w, r, err)
+		}
+		return
+	}
+
+	switch {
+	case handler.SuccessHandler != nil:
+		handler.SuccessHandler(rw, r, at)
+	case rw.written:
+	case handler.SuccessRedirectURL != "":
+		http.Redirect(w, r, handler.SuccessRedirectURL, http.StatusSeeOther)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (handler *LoginHandler) writeTokens(w http.ResponseWriter, r *http.Request, at AuthToken) {
+	ctx := r.Context()
+
+	refreshToken, err := handler.RefreshTokenIssuer.Issue(ctx, at)
+	if err != nil {
+		handler.handleError(w, r, err)
+		return
+	}
+
+	accessToken, err := handler.AuthEngine.SerializerAuthToken(ctx, at)
+	if err != nil {
+		handler.handleError(w, r, err)
+		return
+	}
+
+	_ = WriteTokenResponse(w, at, accessToken, refreshToken, handler.now())
+}
+
+// LogoutHandler revokes server-side state of AuthToken and clears it from client.
+//
+// AuthToken is taken from request context(see SessionMiddleware) or loaded using SessionEngine if it's set.
+// Requests with invalid AuthToken are still logged out, so client can get rid of it.
+// AuthToken is cleared from client even if revocation fails. Revoker returning ErrAuthDataNotSupported
+// means that there is nothing to revoke.
+type LogoutHandler struct {
+	SessionEngine SessionEngine
+	Clearer       HTTPAuthTokenClearer
+	Revoker       AuthTokenRevoker
+
+	// Events receives EventLogout and EventTokenRevoked events. It may be nil.
+	Events *EventBus
+
+	SuccessRedirectURL string
+	// SuccessHandler, if set, is called after logout instead of default behaviour.
+	SuccessHandler func(w http.ResponseWriter, r *http.Request)
+	// ErrorHandler is called when revocation or clearing fails. If nil, DefaultErrorRenderer is used.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+func (handler *LogoutHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if handler.ErrorHandler != nil {
+		handler.ErrorHandler(w, r, err)
+		return
+	}
+	defaultErrorRenderer.RenderError(w, r, err)
+}
+
+func (handler *LogoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	at, ok := FromContext(ctx)
+	if !ok && handler.SessionEngine != nil {
+		// it's fine to fail here, there is just nothing to revoke
+		at, _ = handler.SessionEngine.GetRequestAuthToken(ctx, r)
+	}
+
+	var revokeErr error
+	if at != nil && handler.Revoker != nil {
+		revokeErr = handler.Revoker.RevokeAuthToken(ctx, at)
+		if errors.Is(revokeErr, ErrAuthDataNotSupported) {
+			// there is no server-side state of this AuthToken, so there is nothing to revoke
+			revokeErr = nil
+		} else if revokeErr == nil {
+			handler.Events.Emit(ctx, newEvent(EventTokenRevoked, r, nil, at, nil))
+		}
+	}
+
+	// clear AuthToken even if revocation has failed, so client gets rid of it anyway
+	if handler.Clearer != nil {
+		err := handler.Clearer.ClearAuthToken(ctx, w, r)
+		if err != nil {
+			handler.handleError(w, r, err)
+			return
+		}
+	}
+	if revokeErr != nil {
+		handler.handleError(w, r, revokeErr)
+		return
+	}
+
+	handler.Events.Emit(ctx, newEvent(EventLogout, r, nil, at, nil))
+
+	switch {
+	case handler.SuccessHandler != nil:
+		handler.SuccessHandler(w, r)
+	case handler.SuccessRedirectURL != "":
+		http.Redirect(w, r, handler.SuccessRedirectURL, http.StatusSeeOther)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
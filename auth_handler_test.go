@@ -0,0 +1,73 @@
+package rocho
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type stubRevoker struct {
+	err error
+}
+
+func (sr *stubRevoker) RevokeAuthToken(ctx context.Context, at AuthToken) (err error) {
+	err = sr.err
+	return
+}
+
+type stubClearer struct {
+	cleared bool
+}
+
+func (sc *stubClearer) ClearAuthToken(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+	sc.cleared = true
+	return
+}
+
+func TestLogoutHandler_ClearsAuthTokenWhenRevocationFails(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "revoked", err: nil, code: http.StatusNoContent},
+		{name: "nothing to revoke", err: ErrAuthDataNotSupported, code: http.StatusNoContent},
+		{name: "revocation failed", err: errors.New("store is down"), code: http.StatusInternalServerError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var events []EventType
+			clearer := &stubClearer{}
+			handler := &LogoutHandler{
+				Clearer: clearer,
+				Revoker: &stubRevoker{err: tc.err},
+				Events: &EventBus{
+					Listeners: []EventListener{
+						EventListenerFunc(func(ctx context.Context, e Event) (err error) {
+							events = append(events, e.Type)
+							return
+						}),
+					},
+				},
+			}
+
+			r := httptest.NewRequest("POST", "/logout", nil)
+			r = r.WithContext(NewContext(r.Context(), "token"))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if !clearer.cleared {
+				t.Error("expected AuthToken to be cleared")
+			}
+			if w.Code != tc.code {
+				t.Errorf("expected status %d, got %d", tc.code, w.Code)
+			}
+
+			revoked := len(events) > 0 && events[0] == EventTokenRevoked
+			if revoked != (tc.err == nil) {
+				t.Errorf("unexpected events %v", events)
+			}
+		})
+	}
+}
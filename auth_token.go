@@ -2,9 +2,20 @@ package rocho
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"time"
 )
 
+// ErrNoAuthToken is returned by HTTPAuthTokenSerializer and AuthTokenLoader when request does not contain AuthToken at all.
+// It allows distinguishing anonymous requests from ones with invalid AuthToken.
+var ErrNoAuthToken = errors.New("rocho: No AuthToken in request")
+
+// ErrInvalidAuthRequest is matched by errors of HTTPAuthTokenSerializer, which are caused by malformed request
+// rather than by invalid AuthToken, for instance when token is passed using more than one method.
+// Such requests are answered with 400 rather than 401.
+var ErrInvalidAuthRequest = errors.New("rocho: Malformed authentication request")
+
 // AuthToken contains result of authentication.
 // It should have info about user being authenticated, for instance contain entire user entity.
 //
@@ -16,6 +27,44 @@ type HasSecretInfo interface {
 	StripSecretInfo() // removes secret info from this AuthToken.
 }
 
+// ExpiringAuthToken is AuthToken, which knows when it expires.
+// Zero time means that it never expires.
+type ExpiringAuthToken interface {
+	ExpiresAt() time.Time
+}
+
+// NotBeforeAuthToken is AuthToken, which is not valid before some time.
+// Zero time means that it's valid since it was issued.
+type NotBeforeAuthToken interface {
+	NotBefore() time.Time
+}
+
+// IssuerAuthToken is AuthToken, which knows who issued it.
+type IssuerAuthToken interface {
+	Issuer() string
+}
+
+// AudienceAuthToken is AuthToken, which knows who it's intended for.
+type AudienceAuthToken interface {
+	Audience() []string
+}
+
+// IdentifiedAuthToken is AuthToken, which has unique ID, like "jti" claim of JWT.
+type IdentifiedAuthToken interface {
+	TokenID() string
+}
+
+// IssuedAuthToken is AuthToken, which knows when it was issued.
+// Zero time means that it's not known.
+type IssuedAuthToken interface {
+	IssuedAt() time.Time
+}
+
+// UserAuthToken is AuthToken, which knows ID of user it was issued for.
+type UserAuthToken interface {
+	UserID() string
+}
+
 // AuthTokenValidator is validator, which validates AuthToken.
 // It's responsible for things like expiration.
 type AuthTokenValidator interface {
@@ -72,6 +121,25 @@ type HTTPAuthTokenSerializer interface {
 	DeserializeAuthTokenFromRequest(ctx context.Context, r *http.Request) (at AuthToken, err error)
 }
 
+// AuthChallenger is optionally implemented by HTTPAuthTokenSerializers and AuthTokenLoaders, which tell client
+// how to authenticate, for instance with WWW-Authenticate header.
+// SessionMiddleware uses it for requests, which are rejected as unauthorized.
+type AuthChallenger interface {
+	// Challenge sets challenge headers for error returned while loading AuthToken.
+	// It must not write status code nor body, since these are written by error handler.
+	Challenge(h http.Header, err error)
+}
+
+// HTTPAuthTokenClearer removes AuthToken previously serialized to HTTP response, for instance on logout.
+type HTTPAuthTokenClearer interface {
+	ClearAuthToken(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error)
+}
+
+// AuthTokenRevoker revokes server-side state of AuthToken, so it can't be used anymore, for instance on logout.
+type AuthTokenRevoker interface {
+	RevokeAuthToken(ctx context.Context, at AuthToken) (err error)
+}
+
 // AuthTokenLoader is responsible for loading AuthToken from incoming HTTP request.
 type AuthTokenLoader interface {
 	LoadToken(ctx context.Context, r *http.Request) (at AuthToken, err error)
@@ -22,3 +22,24 @@ func (err *ProviderFiledError) Unwrap() error {
 	}
 	return err.Err
 }
+
+// InvalidAuthTokenError is returned when request contains AuthToken, but it can't be loaded or validated.
+type InvalidAuthTokenError struct {
+	Err error
+}
+
+func (err *InvalidAuthTokenError) Error() string {
+	if err == nil {
+		return "<nil>"
+	}
+	if err.Err == nil {
+		return "rocho: Invalid AuthToken"
+	}
+	return fmt.Sprintf("rocho: Invalid AuthToken: %s", err.Err.Error())
+}
+func (err *InvalidAuthTokenError) Unwrap() error {
+	if err == nil {
+		return nil
+	}
+	return err.Err
+}
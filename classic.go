@@ -3,9 +3,52 @@ package rocho
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 )
 
+// ErrEmptyField is returned when required field of AuthData is empty.
+var ErrEmptyField = errors.New("rocho: Field is empty")
+
+// ErrUnknownField is returned by strict parsers when request contains field, which is not known.
+var ErrUnknownField = errors.New("rocho: Unknown field")
+
+// ErrUnsupportedContentType is returned when AuthDataParser does not support content type of request.
+var ErrUnsupportedContentType = errors.New("rocho: Unsupported content type")
+
+// ErrMalformedAuthData is returned when request body can't be parsed.
+var ErrMalformedAuthData = errors.New("rocho: Malformed AuthData")
+
+// ErrAuthDataTooLarge is returned when request body exceeds size limit of AuthDataParser.
+var ErrAuthDataTooLarge = errors.New("rocho: AuthData is too large")
+
+// AuthDataParseError is returned when AuthData can't be parsed from request.
+// Field is empty if error does not concern single field.
+type AuthDataParseError struct {
+	Field string
+	Err   error
+}
+
+func (err *AuthDataParseError) Error() string {
+	if err == nil {
+		return "<nil>"
+	}
+	if err.Field == "" {
+		return fmt.Sprintf("rocho: Can't parse AuthData: %v", err.Err)
+	}
+	return fmt.Sprintf("rocho: Can't parse AuthData field %q: %v", err.Field, err.Err)
+}
+func (err *AuthDataParseError) Unwrap() error {
+	if err == nil {
+		return nil
+	}
+	return err.Err
+}
+
 // ClassicAuthData contains username and passsword.
 // It's preimplemented for sake of simplicty for end user.
 type ClassicAuthData struct {
@@ -13,14 +56,168 @@ type ClassicAuthData struct {
 	Password string `json:"password"`
 }
 
+// GetUsername returns username.
+func (ad ClassicAuthData) GetUsername() string {
+	return ad.Username
+}
+
+const defaultClassicMaxBodySize = 64 * 1024
+
+// limitedBody is like http.MaxBytesReader, but it remembers if limit was exceeded,
+// since parsers of request body do not preserve errors returned by reader.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (n int, err error) {
+	if b.exceeded {
+		err = ErrAuthDataTooLarge
+		return
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err = b.ReadCloser.Read(p)
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		return
+	}
+	n = int(b.remaining)
+	b.remaining = 0
+	b.exceeded = true
+	err = ErrAuthDataTooLarge
+	return
+}
+
 // ClassicAuthDataParser parses ClassicAuthData from request.
+//
+// It supports JSON, form-encoded and multipart bodies depending on Content-Type of request.
+// Requests without Content-Type are parsed as JSON.
 type ClassicAuthDataParser struct {
+	UsernameField string // if empty, "username" is used
+	PasswordField string // if empty, "password" is used
+
+	// MaxBodySize is max size of request body in bytes. If zero, 64KiB is used.
+	// Larger bodies are rejected with ErrAuthDataTooLarge.
+	MaxBodySize int64
+	// Strict makes parser reject requests containing fields other than username and password.
+	Strict bool
+}
+
+func (adp *ClassicAuthDataParser) fieldNames() (username, password string) {
+	username, password = adp.UsernameField, adp.PasswordField
+	if username == "" {
+		username = "username"
+	}
+	if password == "" {
+		password = "password"
+	}
+	return
 }
 
-// ParseAuthData parses ClassicAuthData from request as if it's JSON.
+// ParseAuthData parses ClassicAuthData from request.
+// Returns *AuthDataParseError if it's not possible or username or password is empty.
 func (adp *ClassicAuthDataParser) ParseAuthData(ctx context.Context, r *http.Request) (ad AuthData, err error) {
-	rad := ClassicAuthData{}
-	err = json.NewDecoder(r.Body).Decode(&rad)
+	maxBodySize := adp.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultClassicMaxBodySize
+	}
+	body := &limitedBody{ReadCloser: r.Body, remaining: maxBodySize}
+	r.Body = body
+
+	mediaType := "application/json"
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err = mime.ParseMediaType(ct)
+		if err != nil {
+			err = &AuthDataParseError{Err: ErrUnsupportedContentType}
+			return
+		}
+	}
+
+	var values url.Values
+	switch mediaType {
+	case "application/json":
+		values, err = adp.parseJSON(r)
+	case "application/x-www-form-urlencoded":
+		err = r.ParseForm()
+		values = r.PostForm
+	case "multipart/form-data":
+		err = r.ParseMultipartForm(maxBodySize)
+		if err == nil {
+			values = r.MultipartForm.Value
+			if adp.Strict {
+				for name := range r.MultipartForm.File {
+					err = &AuthDataParseError{Field: name, Err: ErrUnknownField}
+					return
+				}
+			}
+		}
+	default:
+		err = &AuthDataParseError{Err: ErrUnsupportedContentType}
+		return
+	}
+	if err != nil {
+		var perr *AuthDataParseError
+		if body.exceeded {
+			err = &AuthDataParseError{Err: ErrAuthDataTooLarge}
+		} else if !errors.As(err, &perr) {
+			err = &AuthDataParseError{Err: ErrMalformedAuthData}
+		}
+		return
+	}
+
+	usernameField, passwordField := adp.fieldNames()
+	if adp.Strict {
+		for name := range values {
+			if name != usernameField && name != passwordField {
+				err = &AuthDataParseError{Field: name, Err: ErrUnknownField}
+				return
+			}
+		}
+	}
+
+	rad := ClassicAuthData{
+		Username: values.Get(usernameField),
+		Password: values.Get(passwordField),
+	}
+	if rad.Username == "" {
+		err = &AuthDataParseError{Field: usernameField, Err: ErrEmptyField}
+		return
+	}
+	if rad.Password == "" {
+		err = &AuthDataParseError{Field: passwordField, Err: ErrEmptyField}
+		return
+	}
+
 	ad = rad
 	return
 }
+
+func (adp *ClassicAuthDataParser) parseJSON(r *http.Request) (values url.Values, err error) {
+	fields := map[string]json.RawMessage{}
+	err = json.NewDecoder(r.Body).Decode(&fields)
+	if err != nil {
+		return
+	}
+
+	usernameField, passwordField := adp.fieldNames()
+	values = url.Values{}
+	for name, raw := range fields {
+		if name != usernameField && name != passwordField {
+			// only known fields are decoded, but strict check is done by caller
+			values.Set(name, "")
+			continue
+		}
+
+		var v string
+		err = json.Unmarshal(raw, &v)
+		if err != nil {
+			err = &AuthDataParseError{Field: name, Err: ErrMalformedAuthData}
+			return
+		}
+		values.Set(name, v)
+	}
+	return
+}
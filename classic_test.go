@@ -0,0 +1,196 @@
+package rocho
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func multipartBody(t *testing.T, fields map[string]string, files ...string) (body, contentType string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		err := mw.WriteField(name, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range files {
+		fw, err := mw.CreateFormFile(name, name+".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write([]byte("content"))
+	}
+	err := mw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, contentType = buf.String(), mw.FormDataContentType()
+	return
+}
+
+func TestClassicAuthDataParser(t *testing.T) {
+	validMultipart, multipartType := multipartBody(t, map[string]string{"username": "user", "password": "pass"})
+	fileMultipart, fileMultipartType := multipartBody(t, map[string]string{"username": "user", "password": "pass"}, "avatar")
+	customMultipart, customMultipartType := multipartBody(t, map[string]string{"login": "user", "secret": "pass"})
+	valid := ClassicAuthData{Username: "user", Password: "pass"}
+
+	for name, tc := range map[string]struct {
+		parser      ClassicAuthDataParser
+		contentType string
+		body        string
+		ad          AuthData
+		err         error
+		field       string
+	}{
+		"json": {
+			contentType: "application/json; charset=utf-8",
+			body:        `{"username":"user","password":"pass"}`,
+			ad:          valid,
+		},
+		"json without content type": {
+			body: `{"username":"user","password":"pass"}`,
+			ad:   valid,
+		},
+		"form": {
+			contentType: "application/x-www-form-urlencoded",
+			body:        "username=user&password=pass",
+			ad:          valid,
+		},
+		"multipart": {
+			contentType: multipartType,
+			body:        validMultipart,
+			ad:          valid,
+		},
+		"custom field names": {
+			parser:      ClassicAuthDataParser{UsernameField: "login", PasswordField: "secret"},
+			contentType: customMultipartType,
+			body:        customMultipart,
+			ad:          valid,
+		},
+		"unsupported content type": {
+			contentType: "text/plain",
+			body:        "user:pass",
+			err:         ErrUnsupportedContentType,
+		},
+		"invalid content type": {
+			contentType: "application/json; =",
+			body:        `{"username":"user","password":"pass"}`,
+			err:         ErrUnsupportedContentType,
+		},
+		"malformed json": {
+			body: `{"username":"user",`,
+			err:  ErrMalformedAuthData,
+		},
+		"json with non string field": {
+			body:  `{"username":"user","password":1}`,
+			err:   ErrMalformedAuthData,
+			field: "password",
+		},
+		"empty username": {
+			contentType: "application/x-www-form-urlencoded",
+			body:        "username=&password=pass",
+			err:         ErrEmptyField,
+			field:       "username",
+		},
+		"missing password": {
+			body:  `{"username":"user"}`,
+			err:   ErrEmptyField,
+			field: "password",
+		},
+		"unknown json field": {
+			body: `{"username":"user","password":"pass","admin":true}`,
+			ad:   valid,
+		},
+		"unknown json field in strict mode": {
+			parser: ClassicAuthDataParser{Strict: true},
+			body:   `{"username":"user","password":"pass","admin":true}`,
+			err:    ErrUnknownField,
+			field:  "admin",
+		},
+		"unknown form field in strict mode": {
+			parser:      ClassicAuthDataParser{Strict: true},
+			contentType: "application/x-www-form-urlencoded",
+			body:        "username=user&password=pass&admin=1",
+			err:         ErrUnknownField,
+			field:       "admin",
+		},
+		"file": {
+			contentType: fileMultipartType,
+			body:        fileMultipart,
+			ad:          valid,
+		},
+		"file in strict mode": {
+			parser:      ClassicAuthDataParser{Strict: true},
+			contentType: fileMultipartType,
+			body:        fileMultipart,
+			err:         ErrUnknownField,
+			field:       "avatar",
+		},
+		"body within limit": {
+			parser: ClassicAuthDataParser{MaxBodySize: int64(len(`{"username":"user","password":"pass"}`))},
+			body:   `{"username":"user","password":"pass"}`,
+			ad:     valid,
+		},
+		"json over limit": {
+			parser: ClassicAuthDataParser{MaxBodySize: 64},
+			body:   `{"username":"user","password":"` + strings.Repeat("a", 64) + `"}`,
+			err:    ErrAuthDataTooLarge,
+		},
+		"form over limit": {
+			parser:      ClassicAuthDataParser{MaxBodySize: 64},
+			contentType: "application/x-www-form-urlencoded",
+			body:        "username=user&password=" + strings.Repeat("a", 64),
+			err:         ErrAuthDataTooLarge,
+		},
+		"multipart over limit": {
+			parser:      ClassicAuthDataParser{MaxBodySize: int64(len(validMultipart) - 1)},
+			contentType: multipartType,
+			body:        validMultipart,
+			err:         ErrAuthDataTooLarge,
+		},
+		"over default limit": {
+			body: `{"username":"user","password":"` + strings.Repeat("a", defaultClassicMaxBodySize) + `"}`,
+			err:  ErrAuthDataTooLarge,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/login", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+
+			ad, err := tc.parser.ParseAuthData(context.Background(), r)
+			if ad != tc.ad {
+				t.Errorf("expected %+v, got %+v", tc.ad, ad)
+			}
+			if tc.err == nil {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+
+			var perr *AuthDataParseError
+			if !errors.As(err, &perr) || !errors.Is(err, tc.err) || perr.Field != tc.field {
+				t.Errorf("expected AuthDataParseError of field %q with %v, got %v", tc.field, tc.err, err)
+			}
+		})
+	}
+}
+
+func TestClassicAuthDataParser_TooLargeProblem(t *testing.T) {
+	_, err := (&ClassicAuthDataParser{MaxBodySize: 8}).ParseAuthData(context.Background(), httptest.NewRequest("POST", "/login", strings.NewReader(`{"username":"user","password":"pass"}`)))
+	p := defaultErrorRenderer.Problem(err)
+	if p.Status != http.StatusRequestEntityTooLarge || p.Code != "auth_data_too_large" {
+		t.Errorf("unexpected problem %+v", p)
+	}
+}
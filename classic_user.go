@@ -0,0 +1,196 @@
+package rocho
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/teawithsand/rocho/password"
+)
+
+// ErrUserNotFound is returned by UserStore when there is no user with given username.
+var ErrUserNotFound = errors.New("rocho: User not found")
+
+// ErrInvalidCredentials is returned when username or password is invalid.
+// It's intentionally the same for both cases, so users can't be enumerated.
+var ErrInvalidCredentials = errors.New("rocho: Invalid username or password")
+
+// UserStore keeps users, which log in with username and password.
+type UserStore interface {
+	// LookupUser returns ErrUserNotFound if there is no such user.
+	LookupUser(ctx context.Context, username string) (ud UserData, err error)
+	GetPasswordHash(ctx context.Context, username string) (hash string, err error)
+	UpdatePasswordHash(ctx context.Context, username string, hash string) (err error)
+}
+
+// ClassicUserDataProvider is UserDataProvider, which verifies ClassicAuthData against UserStore.
+//
+// For unknown users dummy hash is verified, so response time does not reveal if user exists.
+// Hashes created with outdated algorithm or parameters are upgraded on successful login.
+//
+// Note: dummy hash is created with current algorithm of Hasher. Until all hashes are upgraded,
+// verification of existing users with legacy or cheaper hashes takes different time than verification of dummy one,
+// so users, who have not logged in since algorithm was changed, may still be enumerated by timing.
+type ClassicUserDataProvider struct {
+	UserStore UserStore
+	// Hasher defaults to password.DefaultHasher, which uses argon2id.
+	Hasher password.Hasher
+
+	dummyOnce sync.Once
+	dummyHash string
+	dummyErr  error
+}
+
+func (p *ClassicUserDataProvider) hasher() password.Hasher {
+	if p.Hasher != nil {
+		return p.Hasher
+	}
+	return &password.DefaultHasher{}
+}
+
+// dummyVerify takes about as much time as verification of real password.
+func (p *ClassicUserDataProvider) dummyVerify(pw string) {
+	p.dummyOnce.Do(func() {
+		p.dummyHash, p.dummyErr = p.hasher().Hash("rocho dummy password")
+	})
+	if p.dummyErr == nil {
+		_, _, _ = p.hasher().Verify(pw, p.dummyHash)
+	}
+}
+
+// GetUserData returns user from UserStore if password matches.
+// Returns ErrAuthDataNotSupported for AuthData other than ClassicAuthData.
+func (p *ClassicUserDataProvider) GetUserData(ctx context.Context, ad AuthData) (ud UserData, err error) {
+	var cad ClassicAuthData
+	switch t := ad.(type) {
+	case ClassicAuthData:
+		cad = t
+	case *ClassicAuthData:
+		cad = *t
+	default:
+		err = ErrAuthDataNotSupported
+		return
+	}
+
+	user, err := p.UserStore.LookupUser(ctx, cad.Username)
+	if errors.Is(err, ErrUserNotFound) {
+		p.dummyVerify(cad.Password)
+		err = ErrInvalidCredentials
+		return
+	} else if err != nil {
+		return
+	}
+
+	hash, err := p.UserStore.GetPasswordHash(ctx, cad.Username)
+	if err != nil {
+		return
+	}
+
+	ok, needsRehash, err := p.hasher().Verify(cad.Password, hash)
+	if err != nil {
+		return
+	}
+	if !ok || cad.Password == "" {
+		err = ErrInvalidCredentials
+		return
+	}
+
+	if needsRehash {
+		// failed upgrade should not prevent user from logging in, it will be retried next time
+		newHash, herr := p.hasher().Hash(cad.Password)
+		if herr == nil {
+			_ = p.UserStore.UpdatePasswordHash(ctx, cad.Username, newHash)
+		}
+	}
+
+	ud = user
+	return
+}
+
+// ClassicAuthenticator is Authenticator for ClassicAuthData.
+// Credentials must be already verified by ClassicUserDataProvider.
+//
+// It returns ErrAuthDataNotSupported for other AuthData.
+type ClassicAuthenticator struct {
+	// NewAuthToken creates AuthToken for user. If nil, UserData is used as AuthToken.
+	NewAuthToken func(ctx context.Context, ud UserData) (AuthToken, error)
+}
+
+func (a *ClassicAuthenticator) Authenticate(ctx context.Context, ad AuthData, ud UserData) (at AuthToken, err error) {
+	switch ad.(type) {
+	case ClassicAuthData, *ClassicAuthData:
+	default:
+		err = ErrAuthDataNotSupported
+		return
+	}
+
+	if a.NewAuthToken == nil {
+		at = ud
+		return
+	}
+	at, err = a.NewAuthToken(ctx, ud)
+	return
+}
+
+type memoryUser struct {
+	ud   UserData
+	hash string
+}
+
+// MemoryUserStore is UserStore, which keeps users in memory.
+// It's intended for tests.
+type MemoryUserStore struct {
+	lock  sync.RWMutex
+	users map[string]memoryUser
+}
+
+// AddUser adds or replaces user with given password hash.
+func (s *MemoryUserStore) AddUser(username string, ud UserData, hash string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.users == nil {
+		s.users = map[string]memoryUser{}
+	}
+	s.users[username] = memoryUser{ud: ud, hash: hash}
+}
+
+func (s *MemoryUserStore) LookupUser(ctx context.Context, username string) (ud UserData, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		err = ErrUserNotFound
+		return
+	}
+	ud = u.ud
+	return
+}
+
+func (s *MemoryUserStore) GetPasswordHash(ctx context.Context, username string) (hash string, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		err = ErrUserNotFound
+		return
+	}
+	hash = u.hash
+	return
+}
+
+func (s *MemoryUserStore) UpdatePasswordHash(ctx context.Context, username string, hash string) (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		err = ErrUserNotFound
+		return
+	}
+	u.hash = hash
+	s.users[username] = u
+	return
+}
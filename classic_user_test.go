@@ -0,0 +1,235 @@
+package rocho
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/teawithsand/rocho/password"
+)
+
+// testHasher is fast password.Hasher, which encodes hashes as "{version}${password}".
+// Hashes of other versions need rehash.
+type testHasher struct {
+	version  string
+	hashed   []string
+	verified []string
+}
+
+func (h *testHasher) Hash(pw string) (encoded string, err error) {
+	h.hashed = append(h.hashed, pw)
+	encoded = h.version + "$" + pw
+	return
+}
+
+func (h *testHasher) Verify(pw, encoded string) (ok, needsRehash bool, err error) {
+	h.verified = append(h.verified, encoded)
+	parts := strings.SplitN(encoded, "$", 2)
+	if len(parts) != 2 {
+		err = password.ErrMalformedHash
+		return
+	}
+	ok = parts[1] == pw
+	needsRehash = ok && parts[0] != h.version
+	return
+}
+
+// failingUpdateStore is UserStore, which fails to update hashes.
+type failingUpdateStore struct {
+	*MemoryUserStore
+}
+
+func (failingUpdateStore) UpdatePasswordHash(ctx context.Context, username string, hash string) (err error) {
+	err = errors.New("update failed")
+	return
+}
+
+func newTestUserStore() *MemoryUserStore {
+	s := &MemoryUserStore{}
+	s.AddUser("current", "current user", "v2$password")
+	s.AddUser("legacy", "legacy user", "v1$password")
+	s.AddUser("empty", "empty user", "v2$")
+	return s
+}
+
+func TestClassicUserDataProvider(t *testing.T) {
+	ctx := context.Background()
+	for name, tc := range map[string]struct {
+		ad       AuthData
+		ud       UserData
+		err      error
+		hash     string
+		verified []string
+	}{
+		"valid": {
+			ad:       ClassicAuthData{Username: "current", Password: "password"},
+			ud:       "current user",
+			hash:     "v2$password",
+			verified: []string{"v2$password"},
+		},
+		"pointer": {
+			ad:       &ClassicAuthData{Username: "current", Password: "password"},
+			ud:       "current user",
+			hash:     "v2$password",
+			verified: []string{"v2$password"},
+		},
+		"invalid password": {
+			ad:       ClassicAuthData{Username: "current", Password: "other"},
+			err:      ErrInvalidCredentials,
+			hash:     "v2$password",
+			verified: []string{"v2$password"},
+		},
+		"empty password": {
+			ad:       ClassicAuthData{Username: "empty"},
+			err:      ErrInvalidCredentials,
+			verified: []string{"v2$"},
+		},
+		"unknown user": {
+			ad:       ClassicAuthData{Username: "unknown", Password: "password"},
+			err:      ErrInvalidCredentials,
+			verified: []string{"v2$rocho dummy password"},
+		},
+		"other AuthData": {
+			ad:  "other",
+			err: ErrAuthDataNotSupported,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := newTestUserStore()
+			hasher := &testHasher{version: "v2"}
+			p := &ClassicUserDataProvider{UserStore: store, Hasher: hasher}
+
+			ud, err := p.GetUserData(ctx, tc.ad)
+			if !errors.Is(err, tc.err) || ud != tc.ud {
+				t.Errorf("expected %v and %v, got %v and %v", tc.ud, tc.err, ud, err)
+			}
+			if strings.Join(hasher.verified, ",") != strings.Join(tc.verified, ",") {
+				t.Errorf("expected %v to be verified, got %v", tc.verified, hasher.verified)
+			}
+			if tc.hash != "" {
+				hash, _ := store.GetPasswordHash(ctx, "current")
+				if hash != tc.hash {
+					t.Errorf("expected hash %q, got %q", tc.hash, hash)
+				}
+			}
+		})
+	}
+}
+
+func TestClassicUserDataProvider_DummyHashCreatedOnce(t *testing.T) {
+	ctx := context.Background()
+	hasher := &testHasher{version: "v2"}
+	p := &ClassicUserDataProvider{UserStore: newTestUserStore(), Hasher: hasher}
+
+	for i := 0; i < 3; i++ {
+		_, err := p.GetUserData(ctx, ClassicAuthData{Username: "unknown", Password: "password"})
+		if !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+		}
+	}
+	if len(hasher.hashed) != 1 || len(hasher.verified) != 3 {
+		t.Errorf("expected single dummy hash verified each time, got %d hashes and %d verifications", len(hasher.hashed), len(hasher.verified))
+	}
+}
+
+func TestClassicUserDataProvider_Rehash(t *testing.T) {
+	ctx := context.Background()
+	store := newTestUserStore()
+	p := &ClassicUserDataProvider{UserStore: store, Hasher: &testHasher{version: "v2"}}
+
+	_, err := p.GetUserData(ctx, ClassicAuthData{Username: "legacy", Password: "other"})
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+	hash, _ := store.GetPasswordHash(ctx, "legacy")
+	if hash != "v1$password" {
+		t.Errorf("expected hash not to be upgraded on failed login, got %q", hash)
+	}
+
+	ud, err := p.GetUserData(ctx, ClassicAuthData{Username: "legacy", Password: "password"})
+	if err != nil || ud != "legacy user" {
+		t.Fatalf("unexpected result %v, %v", ud, err)
+	}
+	hash, _ = store.GetPasswordHash(ctx, "legacy")
+	if hash != "v2$password" {
+		t.Errorf("expected hash to be upgraded, got %q", hash)
+	}
+}
+
+func TestClassicUserDataProvider_RehashFailureDoesNotPreventLogin(t *testing.T) {
+	ctx := context.Background()
+	store := newTestUserStore()
+	p := &ClassicUserDataProvider{UserStore: failingUpdateStore{store}, Hasher: &testHasher{version: "v2"}}
+
+	ud, err := p.GetUserData(ctx, ClassicAuthData{Username: "legacy", Password: "password"})
+	if err != nil || ud != "legacy user" {
+		t.Errorf("unexpected result %v, %v", ud, err)
+	}
+}
+
+// Legacy bcrypt hash is upgraded by password.DefaultHasher.
+func TestClassicUserDataProvider_DefaultHasherUpgrade(t *testing.T) {
+	ctx := context.Background()
+	legacy, err := (&password.Bcrypt{Cost: 4}).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &MemoryUserStore{}
+	store.AddUser("user", "user", legacy)
+
+	hasher := &password.DefaultHasher{Algorithm: &password.PBKDF2SHA256{Iterations: 1000}}
+	p := &ClassicUserDataProvider{UserStore: store, Hasher: hasher}
+	_, err = p.GetUserData(ctx, ClassicAuthData{Username: "user", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, _ := store.GetPasswordHash(ctx, "user")
+	if !strings.HasPrefix(hash, "$pbkdf2-sha256$") {
+		t.Fatalf("expected hash to be upgraded, got %q", hash)
+	}
+	ok, needsRehash, err := hasher.Verify("password", hash)
+	if err != nil || !ok || needsRehash {
+		t.Errorf("expected upgraded hash to be current, got %v, %v, %v", ok, needsRehash, err)
+	}
+}
+
+func TestMemoryUserStore(t *testing.T) {
+	ctx := context.Background()
+	s := &MemoryUserStore{}
+
+	_, err := s.LookupUser(ctx, "user")
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	_, err = s.GetPasswordHash(ctx, "user")
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	err = s.UpdatePasswordHash(ctx, "user", "hash")
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+
+	s.AddUser("user", "first", "hash")
+	err = s.UpdatePasswordHash(ctx, "user", "new hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ud, err := s.LookupUser(ctx, "user")
+	if err != nil || ud != "first" {
+		t.Errorf("unexpected user %v, %v", ud, err)
+	}
+	hash, err := s.GetPasswordHash(ctx, "user")
+	if err != nil || hash != "new hash" {
+		t.Errorf("unexpected hash %q, %v", hash, err)
+	}
+
+	s.AddUser("user", "second", "other hash")
+	ud, _ = s.LookupUser(ctx, "user")
+	hash, _ = s.GetPasswordHash(ctx, "user")
+	if ud != "second" || hash != "other hash" {
+		t.Errorf("expected user to be replaced, got %v with %q", ud, hash)
+	}
+}
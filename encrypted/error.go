@@ -0,0 +1,15 @@
+package encrypted
+
+import "errors"
+
+// ErrMalformedToken is returned when token has invalid format.
+var ErrMalformedToken = errors.New("rocho/encrypted: Malformed token")
+
+// ErrDecryptionFailed is returned when token can't be authenticated and decrypted with key it points to.
+var ErrDecryptionFailed = errors.New("rocho/encrypted: Token decryption failed")
+
+// ErrUnknownKeyID is returned when token was sealed with key, which is not in Keyring.
+var ErrUnknownKeyID = errors.New("rocho/encrypted: Unknown key ID")
+
+// ErrInvalidKey is returned when key has invalid size or unsupported algorithm.
+var ErrInvalidKey = errors.New("rocho/encrypted: Invalid key")
@@ -0,0 +1,72 @@
+package encrypted
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Algorithm is AEAD used to seal tokens.
+type Algorithm uint8
+
+const (
+	AES256GCM Algorithm = iota + 1
+	XChaCha20Poly1305
+)
+
+// KeySize is size of key, which is required by all supported algorithms.
+const KeySize = 32
+
+// Key is single key in Keyring.
+type Key struct {
+	// ID is stored in token, so right key can be found during decryption.
+	// It must not be empty and must be unique in Keyring.
+	ID        string
+	Algorithm Algorithm
+	Secret    []byte // must have KeySize bytes
+}
+
+func (k *Key) aead() (aead cipher.AEAD, err error) {
+	if len(k.Secret) != KeySize {
+		err = ErrInvalidKey
+		return
+	}
+
+	switch k.Algorithm {
+	case AES256GCM:
+		var block cipher.Block
+		block, err = aes.NewCipher(k.Secret)
+		if err != nil {
+			return
+		}
+		aead, err = cipher.NewGCM(block)
+	case XChaCha20Poly1305:
+		aead, err = chacha20poly1305.NewX(k.Secret)
+	default:
+		err = ErrInvalidKey
+	}
+	return
+}
+
+// Keyring contains keys used by Serializer.
+// Only primary key is used for encryption. Secondary keys are used only for decryption of tokens issued before rotation.
+type Keyring struct {
+	Primary   Key
+	Secondary []Key
+}
+
+func (kr *Keyring) findKey(id string) (k *Key, err error) {
+	if kr.Primary.ID == id {
+		k = &kr.Primary
+		return
+	}
+	for i := range kr.Secondary {
+		if kr.Secondary[i].ID == id {
+			k = &kr.Secondary[i]
+			return
+		}
+	}
+	err = ErrUnknownKeyID
+	return
+}
@@ -0,0 +1,117 @@
+// Package encrypted implements rocho.AuthTokenSerializer, which seals AuthTokens with AEAD.
+// Produced tokens are opaque for clients.
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+
+	"github.com/teawithsand/rocho"
+)
+
+var encoding = base64.RawURLEncoding
+
+// Serializer implements rocho.AuthTokenSerializer.
+// It serializes AuthToken to JSON and seals it with primary key from Keyring.
+//
+// Token has form of BASE64URL(key ID) || '.' || BASE64URL(nonce || ciphertext).
+// Key ID part is authenticated as additional data.
+//
+// Since token is encrypted, secret info is not stripped from it.
+// Note: It does not validate expiration. Use AuthTokenValidator for that.
+type Serializer struct {
+	Keyring *Keyring
+
+	// NewAuthToken creates value, which decrypted JSON is unmarshaled to.
+	// It should return pointer. If nil, JSON is unmarshaled to map[string]interface{}.
+	NewAuthToken func() rocho.AuthToken
+}
+
+// SerializeAuthToken encrypts AuthToken with primary key.
+func (s *Serializer) SerializeAuthToken(ctx context.Context, at rocho.AuthToken) (data []byte, err error) {
+	plaintext, err := json.Marshal(at)
+	if err != nil {
+		return
+	}
+
+	key := &s.Keyring.Primary
+	aead, err := key.aead()
+	if err != nil {
+		return
+	}
+
+	header := make([]byte, encoding.EncodedLen(len(key.ID)))
+	encoding.Encode(header, []byte(key.ID))
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, header)
+
+	data = make([]byte, len(header)+1+encoding.EncodedLen(len(sealed)))
+	copy(data, header)
+	data[len(header)] = '.'
+	encoding.Encode(data[len(header)+1:], sealed)
+	return
+}
+
+// DeserializeAuthToken decrypts token with key it points to and unmarshals it to AuthToken.
+func (s *Serializer) DeserializeAuthToken(ctx context.Context, data []byte) (at rocho.AuthToken, err error) {
+	sep := bytes.IndexByte(data, '.')
+	if sep < 0 {
+		err = ErrMalformedToken
+		return
+	}
+	header, body := data[:sep], data[sep+1:]
+
+	keyID, err := encoding.DecodeString(string(header))
+	if err != nil {
+		err = ErrMalformedToken
+		return
+	}
+	sealed, err := encoding.DecodeString(string(body))
+	if err != nil {
+		err = ErrMalformedToken
+		return
+	}
+
+	key, err := s.Keyring.findKey(string(keyID))
+	if err != nil {
+		return
+	}
+	aead, err := key.aead()
+	if err != nil {
+		return
+	}
+
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		err = ErrMalformedToken
+		return
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		err = ErrDecryptionFailed
+		return
+	}
+
+	if s.NewAuthToken != nil {
+		at = s.NewAuthToken()
+	} else {
+		at = &map[string]interface{}{}
+	}
+
+	err = json.Unmarshal(plaintext, at)
+	if err != nil {
+		at = nil
+		return
+	}
+	return
+}
@@ -0,0 +1,140 @@
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/teawithsand/rocho"
+)
+
+type testToken struct {
+	Sub string `json:"sub"`
+}
+
+func newTestSerializer(kr *Keyring) *Serializer {
+	return &Serializer{
+		Keyring: kr,
+		NewAuthToken: func() rocho.AuthToken {
+			return &testToken{}
+		},
+	}
+}
+
+func testKey(id string, alg Algorithm, fill byte) Key {
+	return Key{ID: id, Algorithm: alg, Secret: bytes.Repeat([]byte{fill}, KeySize)}
+}
+
+func TestSerializer_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	for name, alg := range map[string]Algorithm{
+		"AES256GCM":         AES256GCM,
+		"XChaCha20Poly1305": XChaCha20Poly1305,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newTestSerializer(&Keyring{Primary: testKey("k1", alg, 1)})
+
+			data, err := s.SerializeAuthToken(ctx, &testToken{Sub: "user"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(data, []byte("user")) {
+				t.Error("expected token to be opaque")
+			}
+
+			at, err := s.DeserializeAuthToken(ctx, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if at.(*testToken).Sub != "user" {
+				t.Errorf("unexpected token %+v", at)
+			}
+		})
+	}
+}
+
+func TestSerializer_RejectsTamperedToken(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSerializer(&Keyring{Primary: testKey("k1", XChaCha20Poly1305, 1)})
+
+	data, err := s.SerializeAuthToken(ctx, &testToken{Sub: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := append([]byte{}, data...)
+	last := len(tampered) - 2
+	if tampered[last] == 'A' {
+		tampered[last] = 'B'
+	} else {
+		tampered[last] = 'A'
+	}
+	_, err = s.DeserializeAuthToken(ctx, tampered)
+	if !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("expected ErrDecryptionFailed, got %v", err)
+	}
+
+	_, err = s.DeserializeAuthToken(ctx, []byte("no separator"))
+	if !errors.Is(err, ErrMalformedToken) {
+		t.Errorf("expected ErrMalformedToken, got %v", err)
+	}
+}
+
+func TestSerializer_RejectsWrongKey(t *testing.T) {
+	ctx := context.Background()
+	issuer := newTestSerializer(&Keyring{Primary: testKey("k1", AES256GCM, 1)})
+	data, err := issuer.SerializeAuthToken(ctx, &testToken{Sub: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestSerializer(&Keyring{Primary: testKey("k1", AES256GCM, 2)})
+	_, err = s.DeserializeAuthToken(ctx, data)
+	if !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("expected ErrDecryptionFailed, got %v", err)
+	}
+
+	s = newTestSerializer(&Keyring{Primary: testKey("k2", AES256GCM, 1)})
+	_, err = s.DeserializeAuthToken(ctx, data)
+	if !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("expected ErrUnknownKeyID, got %v", err)
+	}
+
+	s = newTestSerializer(&Keyring{Primary: Key{ID: "k1", Algorithm: AES256GCM, Secret: []byte("short")}})
+	_, err = s.SerializeAuthToken(ctx, &testToken{Sub: "user"})
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+}
+
+func TestSerializer_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKey := testKey("k1", AES256GCM, 1)
+	old := newTestSerializer(&Keyring{Primary: oldKey})
+	data, err := old.SerializeAuthToken(ctx, &testToken{Sub: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestSerializer(&Keyring{
+		Primary:   testKey("k2", XChaCha20Poly1305, 2),
+		Secondary: []Key{oldKey},
+	})
+	at, err := s.DeserializeAuthToken(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at.(*testToken).Sub != "user" {
+		t.Errorf("unexpected token %+v", at)
+	}
+
+	fresh, err := s.SerializeAuthToken(ctx, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = old.DeserializeAuthToken(ctx, fresh)
+	if !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("expected new tokens to be sealed with primary key, got %v", err)
+	}
+}
@@ -11,7 +11,8 @@ import (
 type SessionEngine interface {
 	GetRequestAuthToken(ctx context.Context, r *http.Request) (at AuthToken, err error)
 	GetRawAuthToken(ctx context.Context, rat []byte) (at AuthToken, err error)
-	// TODO(teawithsand): refill-only function
+	// RefillAuthToken validates and refills AuthToken, which was already loaded by other means.
+	RefillAuthToken(ctx context.Context, at AuthToken) (rat AuthToken, err error)
 }
 
 // AuthEngine authenticates user using incoming request.
@@ -32,37 +33,17 @@ type DefaultAuthEngine struct {
 
 	AuthTokenSerializer     AuthTokenSerializer
 	HTTPAuthTokenSerializer HTTPAuthTokenSerializer
+
+	// Throttler, if set, limits authentication attempts.
+	Throttler AuthThrottler
+
+	// Events receives EventLogin and EventLoginFailed events. It may be nil.
+	Events *EventBus
 }
 
 // AuthenticateAuthData creates AuthToken for user comming with request.
 func (dae *DefaultAuthEngine) AuthenticateAuthData(ctx context.Context, ad AuthData) (at AuthToken, err error) {
-	var ud UserData
-	for _, udp := range dae.UserDataProviders {
-		ud, err = udp.GetUserData(ctx, ad)
-		if errors.Is(err, ErrAuthDataNotSupported) {
-			ud = nil
-			continue
-		} else if err != nil {
-			return
-		}
-
-		// ud should never be nil anyway
-		// but let's check it
-		if ud != nil {
-			break
-		}
-	}
-
-	if ud == nil {
-		err = ErrNoUserData
-		return
-	}
-
-	at, err = dae.Authenticator.Authenticate(ctx, ad, ud)
-	if err != nil {
-		return
-	}
-
+	at, err = dae.authenticate(ctx, nil, ad)
 	return
 }
 
@@ -70,10 +51,44 @@ func (dae *DefaultAuthEngine) AuthenticateAuthData(ctx context.Context, ad AuthD
 func (dae *DefaultAuthEngine) AuthenticateRequest(ctx context.Context, r *http.Request) (at AuthToken, err error) {
 	ad, err := dae.AuthDataParser.ParseAuthData(ctx, r)
 	if err != nil {
+		dae.Events.Emit(ctx, newEvent(EventLoginFailed, r, nil, nil, err))
 		return
 	}
 
+	at, err = dae.authenticate(ctx, r, ad)
+	return
+}
+
+// authenticate does actual authentication. Request may be nil.
+func (dae *DefaultAuthEngine) authenticate(ctx context.Context, r *http.Request, ad AuthData) (at AuthToken, err error) {
 	var ud UserData
+	defer func() {
+		if err != nil {
+			dae.Events.Emit(ctx, newEvent(EventLoginFailed, r, ad, ud, err))
+			return
+		}
+
+		e := newEvent(EventLogin, r, ad, ud, nil)
+		if uat, ok := at.(UserAuthToken); ok {
+			e.UserID = uat.UserID()
+		}
+		dae.Events.Emit(ctx, e)
+	}()
+
+	if dae.Throttler != nil {
+		err = dae.Throttler.CheckAttempt(ctx, r, ad)
+		if err != nil {
+			return
+		}
+		defer func() {
+			rerr := dae.Throttler.RecordAttempt(ctx, r, ad, err)
+			if rerr != nil && err == nil {
+				at = nil
+				err = rerr
+			}
+		}()
+	}
+
 	for _, udp := range dae.UserDataProviders {
 		ud, err = udp.GetUserData(ctx, ad)
 		if errors.Is(err, ErrAuthDataNotSupported) {
@@ -125,85 +140,113 @@ func (dae *DefaultAuthEngine) SerializerAuthToken(ctx context.Context, at AuthTo
 	return
 }
 
+// RefillPolicy determines how DefaultSessionEngine uses it's AuthTokenRefillers.
+type RefillPolicy uint8
+
+const (
+	// RefillFirstMatch uses only first AuthTokenRefiller, which supports AuthToken.
+	RefillFirstMatch RefillPolicy = iota
+	// RefillChain passes AuthToken through all AuthTokenRefillers, which support it, in order.
+	// Each refiller gets AuthToken returned by previous one.
+	RefillChain
+)
+
 // DefaultSessionEngine implements default behaviour of SessionEngine with rocho's components.
+//
+// AuthToken goes through following stages:
+// load -> AuthTokenValidator.ValidatePreRefill -> AuthTokenRefillers -> AuthTokenValidator.ValidateAfterRefill.
+// Refillers returning ErrAuthDataNotSupported are skipped.
 type DefaultSessionEngine struct {
 	AuthTokenDeserializer     AuthTokenSerializer
 	HTTPAuthTokenDeserializer HTTPAuthTokenSerializer
 	AuthTokenValidator        AuthTokenValidator
 
+	// AuthTokenLoader, if set, is used instead of HTTPAuthTokenDeserializer for loading AuthToken from request.
+	AuthTokenLoader AuthTokenLoader
+
 	AuthTokenRefillers []AuthTokenRefiller
+	RefillPolicy       RefillPolicy
+
+	// Events receives EventAuthTokenRejected events. It may be nil.
+	Events *EventBus
 }
 
 // GetRequestAuthToken gets AuthToken from HTTP request.
 func (dse *DefaultSessionEngine) GetRequestAuthToken(ctx context.Context, r *http.Request) (at AuthToken, err error) {
-	at, err = dse.HTTPAuthTokenDeserializer.DeserializeAuthTokenFromRequest(ctx, r)
-	if err != nil {
+	if dse.AuthTokenLoader != nil {
+		at, err = dse.AuthTokenLoader.LoadToken(ctx, r)
+	} else {
+		at, err = dse.HTTPAuthTokenDeserializer.DeserializeAuthTokenFromRequest(ctx, r)
+	}
+	if errors.Is(err, ErrNoAuthToken) {
+		return
+	} else if err != nil {
+		dse.Events.Emit(ctx, newEvent(EventAuthTokenRejected, r, nil, nil, err))
 		return
 	}
 
-	if dse.AuthTokenValidator != nil {
-		err = dse.AuthTokenValidator.ValidatePreRefill(ctx, at)
-		if err != nil {
-			return
-		}
+	rat, err := dse.RefillAuthToken(ctx, at)
+	if err != nil {
+		dse.Events.Emit(ctx, newEvent(EventAuthTokenRejected, r, nil, at, err))
+		return
 	}
+	at = rat
+	return
+}
 
-	var nat AuthToken
-	for _, atr := range dse.AuthTokenRefillers {
-		nat, err = atr.ProcessAuthToken(ctx, at)
-		if errors.Is(err, ErrAuthDataNotSupported) {
-			continue
-		} else if err != nil {
-			return
-		}
-
-		at = nat
+// GetRawAuthToken creates AuthToken from bytes it's given.
+func (dse *DefaultSessionEngine) GetRawAuthToken(ctx context.Context, rat []byte) (at AuthToken, err error) {
+	at, err = dse.AuthTokenDeserializer.DeserializeAuthToken(ctx, rat)
+	if err != nil {
 		return
 	}
 
+	at, err = dse.RefillAuthToken(ctx, at)
+	return
+}
+
+// RefillAuthToken runs all stages except loading on given AuthToken.
+func (dse *DefaultSessionEngine) RefillAuthToken(ctx context.Context, at AuthToken) (rat AuthToken, err error) {
 	if dse.AuthTokenValidator != nil {
-		err = dse.AuthTokenValidator.ValidateAfterRefill(ctx, at)
+		err = dse.AuthTokenValidator.ValidatePreRefill(ctx, at)
 		if err != nil {
 			return
 		}
 	}
 
-	return
-}
-
-// GetRawAuthToken creates AuthToken from bytes it's given.
-func (dse *DefaultSessionEngine) GetRawAuthToken(ctx context.Context, rat []byte) (at AuthToken, err error) {
-	at, err = dse.AuthTokenDeserializer.DeserializeAuthToken(ctx, rat)
+	at, err = dse.refill(ctx, at)
 	if err != nil {
 		return
 	}
 
 	if dse.AuthTokenValidator != nil {
-		err = dse.AuthTokenValidator.ValidatePreRefill(ctx, at)
+		err = dse.AuthTokenValidator.ValidateAfterRefill(ctx, at)
 		if err != nil {
 			return
 		}
 	}
 
-	var nat AuthToken
+	rat = at
+	return
+}
+
+func (dse *DefaultSessionEngine) refill(ctx context.Context, at AuthToken) (rat AuthToken, err error) {
+	rat = at
 	for _, atr := range dse.AuthTokenRefillers {
-		nat, err = atr.ProcessAuthToken(ctx, at)
+		var nat AuthToken
+		nat, err = atr.ProcessAuthToken(ctx, rat)
 		if errors.Is(err, ErrAuthDataNotSupported) {
+			err = nil
 			continue
 		} else if err != nil {
+			rat = nil
 			return
 		}
 
-		at = nat
-		return
-	}
-
-	if dse.AuthTokenValidator != nil {
-		err = dse.AuthTokenValidator.ValidateAfterRefill(ctx, at)
-		if err != nil {
+		rat = nat
+		if dse.RefillPolicy != RefillChain {
 			return
 		}
 	}
-
 	return
 }
@@ -0,0 +1,229 @@
+package rocho
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type callLog struct {
+	calls []string
+}
+
+func (l *callLog) add(call string) {
+	l.calls = append(l.calls, call)
+}
+
+type stubLoader struct {
+	log *callLog
+	at  AuthToken
+	err error
+}
+
+func (sl *stubLoader) LoadToken(ctx context.Context, r *http.Request) (at AuthToken, err error) {
+	sl.log.add("load")
+	at, err = sl.at, sl.err
+	return
+}
+
+type stubValidator struct {
+	log      *callLog
+	preErr   error
+	afterErr error
+}
+
+func (sv *stubValidator) ValidatePreRefill(ctx context.Context, at AuthToken) (err error) {
+	sv.log.add("pre:" + at.(string))
+	err = sv.preErr
+	return
+}
+
+func (sv *stubValidator) ValidateAfterRefill(ctx context.Context, at AuthToken) (err error) {
+	sv.log.add("after:" + at.(string))
+	err = sv.afterErr
+	return
+}
+
+type stubRefiller struct {
+	log  *callLog
+	name string
+	err  error
+}
+
+func (sr *stubRefiller) ProcessAuthToken(ctx context.Context, at AuthToken) (rat AuthToken, err error) {
+	sr.log.add("refill:" + sr.name)
+	if sr.err != nil {
+		err = sr.err
+		return
+	}
+	rat = at.(string) + "+" + sr.name
+	return
+}
+
+func assertCalls(t *testing.T, log *callLog, expected ...string) {
+	t.Helper()
+	if !reflect.DeepEqual(log.calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, log.calls)
+	}
+}
+
+func TestDefaultSessionEngine_StageOrder(t *testing.T) {
+	log := &callLog{}
+	dse := &DefaultSessionEngine{
+		AuthTokenLoader:    &stubLoader{log: log, at: "t"},
+		AuthTokenValidator: &stubValidator{log: log},
+		AuthTokenRefillers: []AuthTokenRefiller{&stubRefiller{log: log, name: "a"}},
+	}
+
+	at, err := dse.GetRequestAuthToken(context.Background(), httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at != "t+a" {
+		t.Errorf("expected refilled token, got %v", at)
+	}
+	assertCalls(t, log, "load", "pre:t", "refill:a", "after:t+a")
+}
+
+func TestDefaultSessionEngine_AfterRefillValidationRejects(t *testing.T) {
+	log := &callLog{}
+	afterErr := errors.New("after refill")
+	dse := &DefaultSessionEngine{
+		AuthTokenValidator: &stubValidator{log: log, afterErr: afterErr},
+		AuthTokenRefillers: []AuthTokenRefiller{&stubRefiller{log: log, name: "a"}},
+	}
+
+	at, err := dse.RefillAuthToken(context.Background(), "t")
+	if !errors.Is(err, afterErr) {
+		t.Fatalf("expected after refill error, got %v", err)
+	}
+	if at != nil {
+		t.Errorf("expected no token, got %v", at)
+	}
+	assertCalls(t, log, "pre:t", "refill:a", "after:t+a")
+}
+
+func TestDefaultSessionEngine_PreRefillValidationStops(t *testing.T) {
+	log := &callLog{}
+	preErr := errors.New("pre refill")
+	dse := &DefaultSessionEngine{
+		AuthTokenValidator: &stubValidator{log: log, preErr: preErr},
+		AuthTokenRefillers: []AuthTokenRefiller{&stubRefiller{log: log, name: "a"}},
+	}
+
+	_, err := dse.RefillAuthToken(context.Background(), "t")
+	if !errors.Is(err, preErr) {
+		t.Fatalf("expected pre refill error, got %v", err)
+	}
+	assertCalls(t, log, "pre:t")
+}
+
+func TestDefaultSessionEngine_RefillPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		policy   RefillPolicy
+		token    string
+		expected []string
+	}{
+		{
+			name:     "first match",
+			policy:   RefillFirstMatch,
+			token:    "t+b",
+			expected: []string{"pre:t", "refill:a", "refill:b", "after:t+b"},
+		},
+		{
+			name:     "chain",
+			policy:   RefillChain,
+			token:    "t+b+c",
+			expected: []string{"pre:t", "refill:a", "refill:b", "refill:c", "after:t+b+c"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			log := &callLog{}
+			dse := &DefaultSessionEngine{
+				AuthTokenValidator: &stubValidator{log: log},
+				AuthTokenRefillers: []AuthTokenRefiller{
+					&stubRefiller{log: log, name: "a", err: ErrAuthDataNotSupported},
+					&stubRefiller{log: log, name: "b"},
+					&stubRefiller{log: log, name: "c"},
+				},
+				RefillPolicy: tc.policy,
+			}
+
+			at, err := dse.RefillAuthToken(context.Background(), "t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if at != tc.token {
+				t.Errorf("expected %v, got %v", tc.token, at)
+			}
+			assertCalls(t, log, tc.expected...)
+		})
+	}
+}
+
+func TestDefaultSessionEngine_RefillerError(t *testing.T) {
+	log := &callLog{}
+	refillErr := errors.New("refill")
+	dse := &DefaultSessionEngine{
+		AuthTokenValidator: &stubValidator{log: log},
+		AuthTokenRefillers: []AuthTokenRefiller{
+			&stubRefiller{log: log, name: "a", err: refillErr},
+			&stubRefiller{log: log, name: "b"},
+		},
+		RefillPolicy: RefillChain,
+	}
+
+	at, err := dse.RefillAuthToken(context.Background(), "t")
+	if !errors.Is(err, refillErr) {
+		t.Fatalf("expected refill error, got %v", err)
+	}
+	if at != nil {
+		t.Errorf("expected no token, got %v", at)
+	}
+	assertCalls(t, log, "pre:t", "refill:a")
+}
+
+func TestDefaultSessionEngine_RejectedEvents(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		err      error
+		rejected bool
+	}{
+		{name: "no token", err: ErrNoAuthToken, rejected: false},
+		{name: "invalid token", err: errors.New("invalid"), rejected: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var events []Event
+			dse := &DefaultSessionEngine{
+				AuthTokenLoader: &stubLoader{log: &callLog{}, err: tc.err},
+				Events: &EventBus{
+					Listeners: []EventListener{
+						EventListenerFunc(func(ctx context.Context, e Event) (err error) {
+							events = append(events, e)
+							return
+						}),
+					},
+				},
+			}
+
+			_, err := dse.GetRequestAuthToken(context.Background(), httptest.NewRequest("GET", "/", nil))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+
+			if !tc.rejected {
+				if len(events) != 0 {
+					t.Errorf("expected no events, got %v", events)
+				}
+				return
+			}
+			if len(events) != 1 || events[0].Type != EventAuthTokenRejected {
+				t.Errorf("expected single rejected event, got %v", events)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package rocho
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// EventType is type of authentication event.
+type EventType string
+
+const (
+	EventLogin       EventType = "login"
+	EventLoginFailed EventType = "login_failed"
+	EventLogout      EventType = "logout"
+
+	// EventAuthTokenRejected is emitted when request contains AuthToken, which can't be loaded or validated.
+	EventAuthTokenRejected EventType = "auth_token_rejected"
+	EventTokenRefreshed    EventType = "token_refreshed"
+	EventTokenRevoked      EventType = "token_revoked"
+
+	EventPermissionGranted EventType = "permission_granted"
+	EventPermissionDenied  EventType = "permission_denied"
+)
+
+// Event describes something that happened during authentication or authorization.
+// Fields, which are not known for given event, are left empty.
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	UserID       string
+	AuthDataType string
+	ProviderName string
+	RemoteAddr   string
+	Err          error
+
+	// Data contains event-specific data, for instance perm.CheckResult for permission events.
+	Data interface{}
+}
+
+// EventListener handles events emitted by EventBus.
+type EventListener interface {
+	HandleEvent(ctx context.Context, e Event) (err error)
+}
+
+// EventListenerFunc contains function
+type EventListenerFunc func(ctx context.Context, e Event) (err error)
+
+// HandleEvent calls function.
+func (f EventListenerFunc) HandleEvent(ctx context.Context, e Event) (err error) {
+	return f(ctx, e)
+}
+
+// EventBus dispatches events to listeners.
+//
+// Listeners are called synchronously in order, AsyncListeners are called in separate goroutines.
+// Errors returned by listeners and their panics never break authentication. They are passed to ErrorHandler, if it's set.
+//
+// Nil EventBus is valid and drops all events.
+type EventBus struct {
+	Listeners      []EventListener
+	AsyncListeners []EventListener
+	ErrorHandler   func(e Event, err error)
+
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// Emit passes event to all listeners. Time of event is set if it's zero.
+func (eb *EventBus) Emit(ctx context.Context, e Event) {
+	if eb == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = now(eb.Now)
+	}
+
+	for _, l := range eb.Listeners {
+		eb.call(ctx, l, e)
+	}
+
+	if len(eb.AsyncListeners) > 0 {
+		// request context is likely to be cancelled before async listeners finish
+		actx := detachedContext{parent: ctx}
+		for _, l := range eb.AsyncListeners {
+			go eb.call(actx, l, e)
+		}
+	}
+}
+
+func (eb *EventBus) call(ctx context.Context, l EventListener, e Event) {
+	defer func() {
+		if r := recover(); r != nil && eb.ErrorHandler != nil {
+			eb.ErrorHandler(e, fmt.Errorf("rocho: Event listener panicked: %v", r))
+		}
+	}()
+
+	err := l.HandleEvent(ctx, e)
+	if err != nil && eb.ErrorHandler != nil {
+		eb.ErrorHandler(e, err)
+	}
+}
+
+// detachedContext keeps values of parent context, but is never cancelled.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) { return }
+func (detachedContext) Done() <-chan struct{}                   { return nil }
+func (detachedContext) Err() error                              { return nil }
+func (ctx detachedContext) Value(key interface{}) interface{}   { return ctx.parent.Value(key) }
+
+// providerNamer is implemented by UserData of providers.
+type providerNamer interface {
+	ProviderName() string
+}
+
+// newEvent creates event filled with data, which can be extracted from given values.
+// Subject may be UserData or AuthToken. Any of values may be nil.
+func newEvent(et EventType, r *http.Request, ad AuthData, subject interface{}, err error) (e Event) {
+	e.Type = et
+	e.Err = err
+
+	if r != nil {
+		e.RemoteAddr = remoteAddr(r)
+	}
+
+	if ad != nil {
+		e.AuthDataType = fmt.Sprintf("%T", ad)
+		switch oad := ad.(type) {
+		case *OAuth2AuthData:
+			e.ProviderName = oad.OAuth2ServiceName
+		case OAuth2AuthData:
+			e.ProviderName = oad.OAuth2ServiceName
+		}
+	}
+
+	if uat, ok := subject.(UserAuthToken); ok {
+		e.UserID = uat.UserID()
+	}
+	if pn, ok := subject.(providerNamer); ok && e.ProviderName == "" {
+		e.ProviderName = pn.ProviderName()
+	}
+	return
+}
+
+func remoteAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
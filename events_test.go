@@ -0,0 +1,182 @@
+package rocho
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testContextKey string
+
+// waitFor waits for value from channel, so async listeners are synchronized without sleeping.
+func waitFor(t *testing.T, ch <-chan Event) (e Event) {
+	t.Helper()
+	select {
+	case e = <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for async listener")
+	}
+	return
+}
+
+func TestEventBus_ListenerOrder(t *testing.T) {
+	var calls []string
+	listener := func(name string) EventListener {
+		return EventListenerFunc(func(ctx context.Context, e Event) (err error) {
+			calls = append(calls, name+":"+string(e.Type))
+			return
+		})
+	}
+
+	eb := &EventBus{Listeners: []EventListener{listener("first"), listener("second"), listener("third")}}
+	eb.Emit(context.Background(), Event{Type: EventLogin})
+	eb.Emit(context.Background(), Event{Type: EventLogout})
+
+	expected := "first:login,second:login,third:login,first:logout,second:logout,third:logout"
+	if strings.Join(calls, ",") != expected {
+		t.Errorf("expected %s, got %v", expected, calls)
+	}
+}
+
+func TestEventBus_Time(t *testing.T) {
+	now := time.Now()
+	var times []time.Time
+	eb := &EventBus{
+		Listeners: []EventListener{EventListenerFunc(func(ctx context.Context, e Event) (err error) {
+			times = append(times, e.Time)
+			return
+		})},
+		Now: func() time.Time {
+			return now
+		},
+	}
+
+	given := now.Add(-time.Hour)
+	eb.Emit(context.Background(), Event{Type: EventLogin})
+	eb.Emit(context.Background(), Event{Type: EventLogin, Time: given})
+	if len(times) != 2 || !times[0].Equal(now) || !times[1].Equal(given) {
+		t.Errorf("expected time to be set only if zero, got %v", times)
+	}
+}
+
+func TestEventBus_ListenerErrors(t *testing.T) {
+	var lock sync.Mutex
+	var errs []string
+	handled := make(chan Event, 2)
+	failing := errors.New("listener failed")
+
+	eb := &EventBus{
+		Listeners: []EventListener{
+			EventListenerFunc(func(ctx context.Context, e Event) (err error) {
+				panic("sync panic")
+			}),
+			EventListenerFunc(func(ctx context.Context, e Event) (err error) {
+				return failing
+			}),
+		},
+		AsyncListeners: []EventListener{
+			EventListenerFunc(func(ctx context.Context, e Event) (err error) {
+				panic("async panic")
+			}),
+		},
+		ErrorHandler: func(e Event, err error) {
+			lock.Lock()
+			errs = append(errs, err.Error())
+			lock.Unlock()
+			handled <- e
+		},
+	}
+
+	var called bool
+	eb.Listeners = append(eb.Listeners, EventListenerFunc(func(ctx context.Context, e Event) (err error) {
+		called = true
+		return
+	}))
+
+	eb.Emit(context.Background(), Event{Type: EventLogin})
+	if !called {
+		t.Error("expected listeners after failing ones to be called")
+	}
+	for i := 0; i < 3; i++ {
+		if e := waitFor(t, handled); e.Type != EventLogin {
+			t.Errorf("expected event to be passed to ErrorHandler, got %+v", e)
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	joined := strings.Join(errs, "\n")
+	for _, expected := range []string{"sync panic", "listener failed", "async panic"} {
+		if !strings.Contains(joined, expected) {
+			t.Errorf("expected %q to be passed to ErrorHandler, got %v", expected, errs)
+		}
+	}
+}
+
+func TestEventBus_PanicWithoutErrorHandler(t *testing.T) {
+	eb := &EventBus{
+		Listeners: []EventListener{EventListenerFunc(func(ctx context.Context, e Event) (err error) {
+			panic("panic")
+		})},
+	}
+	eb.Emit(context.Background(), Event{Type: EventLogin})
+}
+
+func TestEventBus_AsyncDelivery(t *testing.T) {
+	received := make(chan Event)
+	release := make(chan struct{})
+	var ctxErr error
+	var ctxValue interface{}
+
+	eb := &EventBus{
+		AsyncListeners: []EventListener{EventListenerFunc(func(ctx context.Context, e Event) (err error) {
+			// wait until emitter returns and cancels it's context
+			<-release
+			ctxErr = ctx.Err()
+			ctxValue = ctx.Value(testContextKey("key"))
+			received <- e
+			return
+		})},
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), testContextKey("key"), "value"))
+	eb.Emit(ctx, Event{Type: EventLogin, UserID: "user"})
+	// Emit returned while listener is blocked, so it does not wait for async listeners
+	cancel()
+	close(release)
+
+	e := waitFor(t, received)
+	if e.Type != EventLogin || e.UserID != "user" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if ctxErr != nil || ctxValue != "value" {
+		t.Errorf("expected detached context with parent's values, got %v and %v", ctxErr, ctxValue)
+	}
+}
+
+func TestEventBus_Nil(t *testing.T) {
+	var eb *EventBus
+	eb.Emit(context.Background(), Event{Type: EventLogin})
+}
+
+func TestNewEvent(t *testing.T) {
+	r := httptest.NewRequest("POST", "/login", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	failure := errors.New("failure")
+
+	e := newEvent(EventLoginFailed, r, &OAuth2AuthData{OAuth2ServiceName: "google"}, nil, failure)
+	if e.Type != EventLoginFailed || e.RemoteAddr != "192.0.2.1" || e.ProviderName != "google" ||
+		e.AuthDataType != "*rocho.OAuth2AuthData" || e.Err != failure {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	r.RemoteAddr = "pipe"
+	e = newEvent(EventLogin, r, ClassicAuthData{Username: "user"}, nil, nil)
+	if e.RemoteAddr != "pipe" || e.AuthDataType != "rocho.ClassicAuthData" || e.ProviderName != "" {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
@@ -2,4 +2,7 @@ module github.com/teawithsand/rocho
 
 go 1.15
 
-require golang.org/x/oauth2 v0.0.0-20200902213428-5d25da1a8d43
+require (
+	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a
+	golang.org/x/oauth2 v0.0.0-20200902213428-5d25da1a8d43
+)
@@ -1,8 +1,7 @@
 package internal
 
-// TODO(teawithsand): make use of it
-
+// RequestKey is type of keys rocho stores in request's context, so they can't collide with keys of other packages.
 type RequestKey string
 
-// RequestContextKey is key for context.WithValue method for passing HTTP request to inner components.
-var RequestContextKey RequestKey = ""
+// AuthTokenContextKey is key for context.WithValue method for passing AuthToken loaded by middleware to handlers.
+var AuthTokenContextKey RequestKey = "rocho.AuthToken"
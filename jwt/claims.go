@@ -0,0 +1,114 @@
+package jwt
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audience is value of "aud" claim.
+// It's serialized as single string when it has single value and as array otherwise.
+type Audience []string
+
+func (aud Audience) MarshalJSON() ([]byte, error) {
+	if len(aud) == 1 {
+		return json.Marshal(aud[0])
+	}
+	return json.Marshal([]string(aud))
+}
+
+func (aud *Audience) UnmarshalJSON(data []byte) (err error) {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*aud = Audience{single}
+		return
+	}
+
+	var many []string
+	err = json.Unmarshal(data, &many)
+	if err != nil {
+		return
+	}
+	*aud = Audience(many)
+	return
+}
+
+// Contains checks if audience contains given value.
+func (aud Audience) Contains(v string) bool {
+	for _, a := range aud {
+		if a == v {
+			return true
+		}
+	}
+	return false
+}
+
+// StandardClaims contains registered claims from RFC 7519.
+// It's intended to be embedded in application's AuthToken type.
+//
+// Times are stored as unix timestamps in seconds. Zero means that claim is not set.
+type StandardClaims struct {
+	Jti string   `json:"jti,omitempty"`
+	Iss string   `json:"iss,omitempty"`
+	Sub string   `json:"sub,omitempty"`
+	Aud Audience `json:"aud,omitempty"`
+	Exp int64    `json:"exp,omitempty"`
+	Nbf int64    `json:"nbf,omitempty"`
+	Iat int64    `json:"iat,omitempty"`
+}
+
+// TokenID returns "jti" claim.
+func (c *StandardClaims) TokenID() string {
+	return c.Jti
+}
+
+// Issuer returns "iss" claim.
+func (c *StandardClaims) Issuer() string {
+	return c.Iss
+}
+
+// Subject returns "sub" claim.
+func (c *StandardClaims) Subject() string {
+	return c.Sub
+}
+
+// UserID returns "sub" claim.
+func (c *StandardClaims) UserID() string {
+	return c.Sub
+}
+
+// Audience returns "aud" claim.
+func (c *StandardClaims) Audience() []string {
+	return c.Aud
+}
+
+// ExpiresAt returns "exp" claim. Returns zero time if it's not set.
+func (c *StandardClaims) ExpiresAt() time.Time {
+	return unixTime(c.Exp)
+}
+
+// NotBefore returns "nbf" claim. Returns zero time if it's not set.
+func (c *StandardClaims) NotBefore() time.Time {
+	return unixTime(c.Nbf)
+}
+
+// IssuedAt returns "iat" claim. Returns zero time if it's not set.
+func (c *StandardClaims) IssuedAt() time.Time {
+	return unixTime(c.Iat)
+}
+
+func unixTime(ts int64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
+
+// registeredClaims is used for validation of claims regardless of AuthToken type.
+// Times are float in order to accept any valid NumericDate.
+type registeredClaims struct {
+	Iss string   `json:"iss"`
+	Aud Audience `json:"aud"`
+	Exp float64  `json:"exp"`
+	Nbf float64  `json:"nbf"`
+	Iat float64  `json:"iat"`
+}
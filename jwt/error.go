@@ -0,0 +1,40 @@
+package jwt
+
+import "errors"
+
+// ErrMalformedToken is returned when token is not valid JWS compact serialization.
+var ErrMalformedToken = errors.New("rocho/jwt: Malformed token")
+
+// ErrInvalidSignature is returned when signature of token does not match.
+var ErrInvalidSignature = errors.New("rocho/jwt: Invalid signature")
+
+// ErrUnsupportedAlgorithm is returned when algorithm from token header does not match algorithm of method used
+// or when method is misconfigured.
+var ErrUnsupportedAlgorithm = errors.New("rocho/jwt: Unsupported algorithm")
+
+// ErrNoMethod is returned when Serializer or Encode is used without signing method.
+var ErrNoMethod = errors.New("rocho/jwt: No signing method")
+
+// ErrNoKey is returned when method has no key, which is required for requested operation.
+var ErrNoKey = errors.New("rocho/jwt: No key for operation")
+
+// ErrUnknownKeyID is returned when token's "kid" header does not match any of known keys.
+var ErrUnknownKeyID = errors.New("rocho/jwt: Unknown key ID")
+
+// ErrTokenExpired is returned when "exp" claim is in the past.
+var ErrTokenExpired = errors.New("rocho/jwt: Token has expired")
+
+// ErrTokenNotValidYet is returned when "nbf" claim is in the future.
+var ErrTokenNotValidYet = errors.New("rocho/jwt: Token is not valid yet")
+
+// ErrTokenIssuedInFuture is returned when "iat" claim is in the future.
+var ErrTokenIssuedInFuture = errors.New("rocho/jwt: Token was issued in the future")
+
+// ErrInvalidIssuer is returned when "iss" claim does not match expected issuer.
+var ErrInvalidIssuer = errors.New("rocho/jwt: Invalid issuer")
+
+// ErrInvalidAudience is returned when "aud" claim does not contain expected audience.
+var ErrInvalidAudience = errors.New("rocho/jwt: Invalid audience")
+
+// ErrNotObject is returned when AuthToken is not serialized to JSON object, so claims can't be set on it.
+var ErrNotObject = errors.New("rocho/jwt: AuthToken must be serialized to JSON object")
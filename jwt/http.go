@@ -0,0 +1,78 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+const bearerScheme = "Bearer"
+
+// HTTPSerializer implements rocho.HTTPAuthTokenSerializer using Serializer.
+//
+// Token is read from "Authorization: Bearer <token>" header and written to response as JSON body
+// in format described in RFC 6749 section 5.1, so it can be used with DefaultAuthEngine and DefaultSessionEngine directly.
+//
+// For cookies, tokens passed in query or form and WWW-Authenticate challenges
+// use transport.Cookie or transport.Bearer with Serializer instead.
+type HTTPSerializer struct {
+	Serializer
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in,omitempty"`
+}
+
+// SerializeAuthTokenToResponse encodes AuthToken as JWT and writes it as JSON body.
+func (hs *HTTPSerializer) SerializeAuthTokenToResponse(ctx context.Context, at rocho.AuthToken, w http.ResponseWriter) (err error) {
+	data, err := hs.SerializeAuthToken(ctx, at)
+	if err != nil {
+		return
+	}
+
+	res := tokenResponse{
+		AccessToken: string(data),
+		TokenType:   bearerScheme,
+	}
+	var expiresAt time.Time
+	if eat, ok := at.(rocho.ExpiringAuthToken); ok {
+		expiresAt = eat.ExpiresAt()
+	}
+	if !expiresAt.IsZero() {
+		res.ExpiresIn = int64(expiresAt.Sub(hs.now()) / time.Second)
+	} else if hs.TTL > 0 {
+		res.ExpiresIn = int64(hs.TTL / time.Second)
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(res)
+	return
+}
+
+// DeserializeAuthTokenFromRequest reads JWT from Authorization header and deserializes AuthToken from it.
+// Returns rocho.ErrNoAuthToken if there is no bearer token in request.
+func (hs *HTTPSerializer) DeserializeAuthTokenFromRequest(ctx context.Context, r *http.Request) (at rocho.AuthToken, err error) {
+	header := r.Header.Get("Authorization")
+	// scheme is case-insensitive
+	if len(header) <= len(bearerScheme) || !strings.EqualFold(header[:len(bearerScheme)], bearerScheme) || header[len(bearerScheme)] != ' ' {
+		err = rocho.ErrNoAuthToken
+		return
+	}
+	token := strings.TrimSpace(header[len(bearerScheme)+1:])
+	if token == "" {
+		err = rocho.ErrNoAuthToken
+		return
+	}
+
+	at, err = hs.DeserializeAuthToken(ctx, []byte(token))
+	return
+}
@@ -0,0 +1,115 @@
+// Package jwt implements rocho.AuthTokenSerializer and rocho.HTTPAuthTokenSerializer, which serialize AuthTokens to JWS compact JWTs.
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Header is JOSE header of JWT.
+type Header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// KeyFunc chooses method used to verify token with given header.
+type KeyFunc func(h Header) (m Method, err error)
+
+var encoding = base64.RawURLEncoding
+
+// Encode creates signed JWT with given payload using given method.
+// Payload must be JSON-encoded claims.
+func Encode(m Method, keyID string, payload []byte) (token []byte, err error) {
+	if m == nil {
+		err = ErrNoMethod
+		return
+	}
+
+	rawHeader, err := json.Marshal(Header{
+		Algorithm: m.Algorithm(),
+		Type:      "JWT",
+		KeyID:     keyID,
+	})
+	if err != nil {
+		return
+	}
+
+	signingInput := make([]byte, 0, encoding.EncodedLen(len(rawHeader))+1+encoding.EncodedLen(len(payload)))
+	signingInput = appendEncoded(signingInput, rawHeader)
+	signingInput = append(signingInput, '.')
+	signingInput = appendEncoded(signingInput, payload)
+
+	sig, err := m.Sign(signingInput)
+	if err != nil {
+		return
+	}
+
+	token = append(signingInput, '.')
+	token = appendEncoded(token, sig)
+	return
+}
+
+// Parse verifies signature of JWT using method returned by KeyFunc and returns its header and payload.
+// Algorithm from header must match algorithm of returned method.
+//
+// Note: It does not validate any claims.
+func Parse(token []byte, kf KeyFunc) (h Header, payload []byte, err error) {
+	parts := bytes.Split(token, []byte("."))
+	if len(parts) != 3 {
+		err = ErrMalformedToken
+		return
+	}
+
+	rawHeader, err := decode(parts[0])
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(rawHeader, &h)
+	if err != nil {
+		err = ErrMalformedToken
+		return
+	}
+
+	m, err := kf(h)
+	if err != nil {
+		return
+	}
+	// Never let token choose algorithm.
+	if m == nil || h.Algorithm != m.Algorithm() {
+		err = ErrUnsupportedAlgorithm
+		return
+	}
+
+	sig, err := decode(parts[2])
+	if err != nil {
+		return
+	}
+
+	err = m.Verify(token[:len(parts[0])+1+len(parts[1])], sig)
+	if err != nil {
+		return
+	}
+
+	payload, err = decode(parts[1])
+	return
+}
+
+func appendEncoded(dst, src []byte) []byte {
+	n := len(dst)
+	dst = append(dst, make([]byte, encoding.EncodedLen(len(src)))...)
+	encoding.Encode(dst[n:], src)
+	return dst
+}
+
+func decode(src []byte) (res []byte, err error) {
+	res = make([]byte, encoding.DecodedLen(len(src)))
+	n, err := encoding.Decode(res, src)
+	if err != nil {
+		err = ErrMalformedToken
+		return
+	}
+	res = res[:n]
+	return
+}
@@ -0,0 +1,313 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	res, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func staticKey(m Method) KeyFunc {
+	return func(h Header) (Method, error) {
+		return m, nil
+	}
+}
+
+// RFC 7515 appendix A.1
+func TestParse_RFC7515HS256(t *testing.T) {
+	const token = "eyJ0eXAiOiJKV1QiLA0KICJhbGciOiJIUzI1NiJ9" +
+		".eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ" +
+		".dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	m := &HMAC{
+		Hash: crypto.SHA256,
+		Key:  mustDecode(t, "AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow"),
+	}
+
+	h, payload, err := Parse([]byte(token), staticKey(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Algorithm != "HS256" || h.Type != "JWT" {
+		t.Errorf("unexpected header %+v", h)
+	}
+	expected := "{\"iss\":\"joe\",\r\n \"exp\":1300819380,\r\n \"http://example.com/is_root\":true}"
+	if string(payload) != expected {
+		t.Errorf("unexpected payload %q", payload)
+	}
+
+	tampered := strings.Replace(token, ".dBjf", ".dBjg", 1)
+	_, _, err = Parse([]byte(tampered), staticKey(m))
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+// RFC 8037 appendix A.4
+func TestParse_RFC8037Ed25519(t *testing.T) {
+	const token = "eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc" +
+		".hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg"
+	seed := mustDecode(t, "nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A")
+	pub := mustDecode(t, "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+
+	_, payload, err := Parse([]byte(token), staticKey(&Ed25519{PublicKey: ed25519.PublicKey(pub)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != "Example of Ed25519 signing" {
+		t.Errorf("unexpected payload %q", payload)
+	}
+
+	// Ed25519 is deterministic, so signature over the same input must be the same
+	priv := ed25519.NewKeyFromSeed(seed)
+	sig, err := (&Ed25519{PrivateKey: priv}).Sign([]byte(token[:strings.LastIndex(token, ".")]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base64.RawURLEncoding.EncodeToString(sig) != token[strings.LastIndex(token, ".")+1:] {
+		t.Error("signature does not match test vector")
+	}
+}
+
+func TestEncodeParse_RoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []Method{
+		&HMAC{Hash: crypto.SHA256, Key: []byte("secret")},
+		&HMAC{Hash: crypto.SHA384, Key: []byte("secret")},
+		&HMAC{Hash: crypto.SHA512, Key: []byte("secret")},
+		&RSA{PrivateKey: rsaKey},
+		&ECDSA{PrivateKey: ecKey},
+		&Ed25519{PrivateKey: edKey},
+	} {
+		t.Run(m.Algorithm(), func(t *testing.T) {
+			token, err := Encode(m, "kid", []byte(`{"sub":"user"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h, payload, err := Parse(token, staticKey(m))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Algorithm != m.Algorithm() || h.KeyID != "kid" || string(payload) != `{"sub":"user"}` {
+				t.Errorf("unexpected result %+v %q", h, payload)
+			}
+		})
+	}
+}
+
+func TestParse_RejectsAlgorithmMismatch(t *testing.T) {
+	hmacKey := &HMAC{Hash: crypto.SHA256, Key: []byte("secret")}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := Encode(hmacKey, "", []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = Parse(token, staticKey(&RSA{PrivateKey: rsaKey}))
+	if !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("expected ErrUnsupportedAlgorithm, got %v", err)
+	}
+
+	_, _, err = Parse(token, staticKey(&HMAC{Hash: crypto.SHA512, Key: []byte("secret")}))
+	if !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("expected ErrUnsupportedAlgorithm, got %v", err)
+	}
+}
+
+func TestParse_RejectsNone(t *testing.T) {
+	enc := base64.RawURLEncoding
+	for _, token := range []string{
+		enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(`{}`)) + ".",
+		enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(`{}`)),
+	} {
+		_, _, err := Parse([]byte(token), staticKey(&HMAC{Hash: crypto.SHA256, Key: []byte("secret")}))
+		if err == nil {
+			t.Errorf("expected %q to be rejected", token)
+		}
+	}
+}
+
+func TestSerializer(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1600000000, 0)
+	clock := func() time.Time {
+		return now
+	}
+	oldKey := &HMAC{Hash: crypto.SHA256, Key: []byte("old")}
+	s := &Serializer{
+		Method:   &HMAC{Hash: crypto.SHA256, Key: []byte("new")},
+		KeyID:    "new",
+		Keys:     map[string]Method{"old": oldKey},
+		Issuer:   "issuer",
+		Audience: []string{"app"},
+		TTL:      time.Hour,
+		Now:      clock,
+		NewAuthToken: func() rocho.AuthToken {
+			return &StandardClaims{}
+		},
+	}
+
+	data, err := s.SerializeAuthToken(ctx, &StandardClaims{Sub: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at, err := s.DeserializeAuthToken(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := at.(*StandardClaims)
+	if c.Sub != "user" || c.Iss != "issuer" || !c.Aud.Contains("app") || c.ExpiresAt() != now.Add(time.Hour) {
+		t.Errorf("unexpected claims %+v", c)
+	}
+
+	rotated, err := Encode(oldKey, "old", []byte(`{"sub":"user","iss":"issuer","aud":"app"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.DeserializeAuthToken(ctx, rotated)
+	if err != nil {
+		t.Errorf("expected token signed with rotated key to be accepted, got %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		method  Method
+		keyID   string
+		payload string
+		err     error
+	}{
+		"unknown key":      {method: oldKey, keyID: "other", payload: `{"iss":"issuer","aud":"app"}`, err: ErrUnknownKeyID},
+		"wrong key":        {method: oldKey, keyID: "new", payload: `{"iss":"issuer","aud":"app"}`, err: ErrInvalidSignature},
+		"expired":          {method: s.Method, keyID: "new", payload: `{"iss":"issuer","aud":"app","exp":1599999999}`, err: ErrTokenExpired},
+		"not valid yet":    {method: s.Method, keyID: "new", payload: `{"iss":"issuer","aud":"app","nbf":1600000001}`, err: ErrTokenNotValidYet},
+		"wrong issuer":     {method: s.Method, keyID: "new", payload: `{"iss":"other","aud":"app"}`, err: ErrInvalidIssuer},
+		"wrong audience":   {method: s.Method, keyID: "new", payload: `{"iss":"issuer","aud":["other"]}`, err: ErrInvalidAudience},
+		"issued in future": {method: s.Method, keyID: "new", payload: `{"iss":"issuer","aud":"app","iat":1600000001}`, err: ErrTokenIssuedInFuture},
+	} {
+		t.Run(name, func(t *testing.T) {
+			token, err := Encode(tc.method, tc.keyID, []byte(tc.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = s.DeserializeAuthToken(ctx, token)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestSerializer_NoMethod(t *testing.T) {
+	_, err := NewSerializer(nil, "")
+	if !errors.Is(err, ErrNoMethod) {
+		t.Errorf("expected ErrNoMethod from constructor, got %v", err)
+	}
+
+	_, err = (&Serializer{}).SerializeAuthToken(context.Background(), &StandardClaims{Sub: "user"})
+	if !errors.Is(err, ErrNoMethod) {
+		t.Errorf("expected ErrNoMethod from SerializeAuthToken, got %v", err)
+	}
+
+	_, err = Encode(nil, "", []byte("{}"))
+	if !errors.Is(err, ErrNoMethod) {
+		t.Errorf("expected ErrNoMethod from Encode, got %v", err)
+	}
+
+	s, err := NewSerializer(&HMAC{Hash: crypto.SHA256, Key: []byte("key")}, "kid")
+	if err != nil || s.KeyID != "kid" {
+		t.Errorf("unexpected result %+v, %v", s, err)
+	}
+}
+
+func TestHTTPSerializer(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1600000000, 0)
+	var hs rocho.HTTPAuthTokenSerializer = &HTTPSerializer{Serializer{
+		Method: &HMAC{Hash: crypto.SHA256, Key: []byte("key")},
+		TTL:    time.Hour,
+		Now: func() time.Time {
+			return now
+		},
+		NewAuthToken: func() rocho.AuthToken {
+			return &StandardClaims{}
+		},
+	}}
+
+	w := httptest.NewRecorder()
+	err := hs.SerializeAuthTokenToResponse(ctx, &StandardClaims{Sub: "user"}, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Header().Get("Content-Type") != "application/json;charset=UTF-8" || w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+	var res tokenResponse
+	err = json.NewDecoder(w.Body).Decode(&res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TokenType != "Bearer" || res.ExpiresIn != 3600 {
+		t.Errorf("unexpected response %+v", res)
+	}
+
+	for name, tc := range map[string]struct {
+		authorization string
+		err           error
+	}{
+		"bearer":           {authorization: "Bearer " + res.AccessToken},
+		"lowercase scheme": {authorization: "bearer " + res.AccessToken},
+		"no header":        {err: rocho.ErrNoAuthToken},
+		"other scheme":     {authorization: "Basic dXNlcjpwYXNz", err: rocho.ErrNoAuthToken},
+		"empty token":      {authorization: "Bearer ", err: rocho.ErrNoAuthToken},
+		"invalid token":    {authorization: "Bearer " + res.AccessToken + "x", err: ErrInvalidSignature},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tc.authorization != "" {
+				r.Header.Set("Authorization", tc.authorization)
+			}
+			at, err := hs.DeserializeAuthTokenFromRequest(ctx, r)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+			if err == nil && at.(*StandardClaims).Sub != "user" {
+				t.Errorf("unexpected token %+v", at)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA-256 for crypto.Hash
+	_ "crypto/sha512" // registers SHA-384 and SHA-512 for crypto.Hash
+	"math/big"
+)
+
+// Method signs and verifies JWS signatures with single algorithm and key.
+type Method interface {
+	// Algorithm returns value of "alg" header for this method, for instance "HS256".
+	Algorithm() string
+
+	Sign(data []byte) (sig []byte, err error)
+	Verify(data, sig []byte) (err error)
+}
+
+// HMAC implements HS256, HS384 and HS512 algorithms.
+// Algorithm is chosen depending on hash, which must be one of crypto.SHA256, crypto.SHA384 or crypto.SHA512.
+type HMAC struct {
+	Hash crypto.Hash
+	Key  []byte
+}
+
+func (m *HMAC) Algorithm() string {
+	switch m.Hash {
+	case crypto.SHA256:
+		return "HS256"
+	case crypto.SHA384:
+		return "HS384"
+	case crypto.SHA512:
+		return "HS512"
+	}
+	return ""
+}
+
+func (m *HMAC) Sign(data []byte) (sig []byte, err error) {
+	if m.Algorithm() == "" {
+		err = ErrUnsupportedAlgorithm
+		return
+	}
+	if len(m.Key) == 0 {
+		err = ErrNoKey
+		return
+	}
+
+	h := hmac.New(m.Hash.New, m.Key)
+	_, err = h.Write(data)
+	if err != nil {
+		return
+	}
+	sig = h.Sum(nil)
+	return
+}
+
+func (m *HMAC) Verify(data, sig []byte) (err error) {
+	expected, err := m.Sign(data)
+	if err != nil {
+		return
+	}
+	if !hmac.Equal(expected, sig) {
+		err = ErrInvalidSignature
+	}
+	return
+}
+
+// RSA implements RS256 algorithm.
+// PrivateKey is required only for signing. If PublicKey is nil, public part of PrivateKey is used.
+type RSA struct {
+	PrivateKey *rsa.PrivateKey
+	PublicKey  *rsa.PublicKey
+}
+
+func (*RSA) Algorithm() string {
+	return "RS256"
+}
+
+func (m *RSA) Sign(data []byte) (sig []byte, err error) {
+	if m.PrivateKey == nil {
+		err = ErrNoKey
+		return
+	}
+	digest := hashData(crypto.SHA256, data)
+	sig, err = rsa.SignPKCS1v15(rand.Reader, m.PrivateKey, crypto.SHA256, digest)
+	return
+}
+
+func (m *RSA) Verify(data, sig []byte) (err error) {
+	pub := m.PublicKey
+	if pub == nil && m.PrivateKey != nil {
+		pub = &m.PrivateKey.PublicKey
+	}
+	if pub == nil {
+		err = ErrNoKey
+		return
+	}
+	digest := hashData(crypto.SHA256, data)
+	if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest, sig) != nil {
+		err = ErrInvalidSignature
+	}
+	return
+}
+
+// ECDSA implements ES256 algorithm. Keys must use P-256 curve.
+// PrivateKey is required only for signing. If PublicKey is nil, public part of PrivateKey is used.
+type ECDSA struct {
+	PrivateKey *ecdsa.PrivateKey
+	PublicKey  *ecdsa.PublicKey
+}
+
+func (*ECDSA) Algorithm() string {
+	return "ES256"
+}
+
+const es256KeySize = 32
+
+func (m *ECDSA) Sign(data []byte) (sig []byte, err error) {
+	if m.PrivateKey == nil {
+		err = ErrNoKey
+		return
+	}
+	if m.PrivateKey.Curve != elliptic.P256() {
+		err = ErrUnsupportedAlgorithm
+		return
+	}
+
+	r, s, err := ecdsa.Sign(rand.Reader, m.PrivateKey, hashData(crypto.SHA256, data))
+	if err != nil {
+		return
+	}
+
+	// JWS uses fixed size R || S encoding rather than ASN.1
+	sig = make([]byte, 2*es256KeySize)
+	rb, sb := r.Bytes(), s.Bytes()
+	copy(sig[es256KeySize-len(rb):es256KeySize], rb)
+	copy(sig[2*es256KeySize-len(sb):], sb)
+	return
+}
+
+func (m *ECDSA) Verify(data, sig []byte) (err error) {
+	pub := m.PublicKey
+	if pub == nil && m.PrivateKey != nil {
+		pub = &m.PrivateKey.PublicKey
+	}
+	if pub == nil {
+		err = ErrNoKey
+		return
+	}
+	if pub.Curve != elliptic.P256() {
+		err = ErrUnsupportedAlgorithm
+		return
+	}
+	if len(sig) != 2*es256KeySize {
+		err = ErrInvalidSignature
+		return
+	}
+
+	r := new(big.Int).SetBytes(sig[:es256KeySize])
+	s := new(big.Int).SetBytes(sig[es256KeySize:])
+	if !ecdsa.Verify(pub, hashData(crypto.SHA256, data), r, s) {
+		err = ErrInvalidSignature
+	}
+	return
+}
+
+// Ed25519 implements EdDSA algorithm with Ed25519 curve.
+// PrivateKey is required only for signing. If PublicKey is nil, public part of PrivateKey is used.
+type Ed25519 struct {
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+}
+
+func (*Ed25519) Algorithm() string {
+	return "EdDSA"
+}
+
+func (m *Ed25519) Sign(data []byte) (sig []byte, err error) {
+	if len(m.PrivateKey) != ed25519.PrivateKeySize {
+		err = ErrNoKey
+		return
+	}
+	sig = ed25519.Sign(m.PrivateKey, data)
+	return
+}
+
+func (m *Ed25519) Verify(data, sig []byte) (err error) {
+	pub := m.PublicKey
+	if pub == nil && len(m.PrivateKey) == ed25519.PrivateKeySize {
+		pub = m.PrivateKey.Public().(ed25519.PublicKey)
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		err = ErrNoKey
+		return
+	}
+	if !ed25519.Verify(pub, data, sig) {
+		err = ErrInvalidSignature
+	}
+	return
+}
+
+func hashData(h crypto.Hash, data []byte) []byte {
+	hh := h.New()
+	_, _ = hh.Write(data)
+	return hh.Sum(nil)
+}
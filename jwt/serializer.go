@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"math"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+// Serializer implements rocho.AuthTokenSerializer.
+// It serializes AuthToken to JSON and uses it as payload of signed JWT.
+//
+// AuthToken must be serialized to JSON object. It's suggested to embed StandardClaims in it.
+type Serializer struct {
+	// Method is used for signing tokens and verifying tokens without "kid" header or with "kid" equal to KeyID.
+	Method Method
+	KeyID  string
+
+	// Keys contains additional methods used for verification only, keyed by "kid".
+	// It allows rotating keys without invalidating issued tokens.
+	Keys map[string]Method
+
+	// NewAuthToken creates value, which payload is unmarshaled to.
+	// It should return pointer. If nil, payload is unmarshaled to map[string]interface{}.
+	NewAuthToken func() rocho.AuthToken
+
+	// Issuer is set as "iss" claim if AuthToken does not set it and is required during deserialization if not empty.
+	Issuer string
+	// Audience is set as "aud" claim if AuthToken does not set it.
+	// During deserialization token must contain at least one of values if not empty.
+	Audience []string
+	// TTL is used to set "exp" claim if AuthToken does not set it. Zero means no expiration.
+	TTL time.Duration
+	// GenerateTokenID makes serializer set random "jti" claim if AuthToken does not set it.
+	// It's required for revocation of single tokens.
+	GenerateTokenID bool
+
+	// Leeway is clock skew tolerated when checking time-based claims.
+	Leeway time.Duration
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// NewSerializer creates Serializer, which signs tokens with given method.
+// It returns ErrNoMethod if method is nil, so misconfiguration is detected on startup rather than on first login.
+func NewSerializer(m Method, keyID string) (s *Serializer, err error) {
+	if m == nil {
+		err = ErrNoMethod
+		return
+	}
+	s = &Serializer{
+		Method: m,
+		KeyID:  keyID,
+	}
+	return
+}
+
+func (s *Serializer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Serializer) keyFunc(h Header) (m Method, err error) {
+	if h.KeyID == "" || h.KeyID == s.KeyID {
+		m = s.Method
+		return
+	}
+
+	m, ok := s.Keys[h.KeyID]
+	if !ok {
+		err = ErrUnknownKeyID
+	}
+	return
+}
+
+// SerializeAuthToken strips secret info from AuthToken and encodes it as JWT.
+// Returns ErrNoMethod if Method is not set.
+func (s *Serializer) SerializeAuthToken(ctx context.Context, at rocho.AuthToken) (data []byte, err error) {
+	if s.Method == nil {
+		err = ErrNoMethod
+		return
+	}
+
+	hsi, ok := at.(rocho.HasSecretInfo)
+	if ok {
+		hsi.StripSecretInfo()
+	}
+
+	rawClaims, err := json.Marshal(at)
+	if err != nil {
+		return
+	}
+
+	claims := map[string]json.RawMessage{}
+	err = json.Unmarshal(rawClaims, &claims)
+	if err != nil || claims == nil {
+		err = ErrNotObject
+		return
+	}
+
+	now := s.now()
+	setDefault := func(name string, v interface{}) {
+		if _, ok := claims[name]; ok {
+			return
+		}
+		raw, merr := json.Marshal(v)
+		if merr == nil {
+			claims[name] = raw
+		}
+	}
+
+	setDefault("iat", now.Unix())
+	if s.Issuer != "" {
+		setDefault("iss", s.Issuer)
+	}
+	if len(s.Audience) > 0 {
+		setDefault("aud", Audience(s.Audience))
+	}
+	if s.TTL > 0 {
+		setDefault("exp", now.Add(s.TTL).Unix())
+	}
+	if s.GenerateTokenID {
+		if _, ok := claims["jti"]; !ok {
+			rawID := make([]byte, 16)
+			_, err = io.ReadFull(rand.Reader, rawID)
+			if err != nil {
+				return
+			}
+			setDefault("jti", encoding.EncodeToString(rawID))
+		}
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return
+	}
+
+	data, err = Encode(s.Method, s.KeyID, payload)
+	return
+}
+
+// DeserializeAuthToken verifies JWT, validates its claims and unmarshals its payload to AuthToken.
+func (s *Serializer) DeserializeAuthToken(ctx context.Context, data []byte) (at rocho.AuthToken, err error) {
+	_, payload, err := Parse(data, s.keyFunc)
+	if err != nil {
+		return
+	}
+
+	var rc registeredClaims
+	err = json.Unmarshal(payload, &rc)
+	if err != nil {
+		err = ErrMalformedToken
+		return
+	}
+
+	err = s.validateClaims(&rc)
+	if err != nil {
+		return
+	}
+
+	if s.NewAuthToken != nil {
+		at = s.NewAuthToken()
+	} else {
+		at = &map[string]interface{}{}
+	}
+
+	err = json.Unmarshal(payload, at)
+	if err != nil {
+		at = nil
+		return
+	}
+	return
+}
+
+func (s *Serializer) validateClaims(rc *registeredClaims) (err error) {
+	now := s.now()
+
+	if rc.Exp != 0 && now.Add(-s.Leeway).After(numericDate(rc.Exp)) {
+		err = ErrTokenExpired
+		return
+	}
+	if rc.Nbf != 0 && now.Add(s.Leeway).Before(numericDate(rc.Nbf)) {
+		err = ErrTokenNotValidYet
+		return
+	}
+	if rc.Iat != 0 && now.Add(s.Leeway).Before(numericDate(rc.Iat)) {
+		err = ErrTokenIssuedInFuture
+		return
+	}
+
+	if s.Issuer != "" && rc.Iss != s.Issuer {
+		err = ErrInvalidIssuer
+		return
+	}
+
+	if len(s.Audience) > 0 {
+		for _, aud := range s.Audience {
+			if rc.Aud.Contains(aud) {
+				return
+			}
+		}
+		err = ErrInvalidAudience
+		return
+	}
+
+	return
+}
+
+func numericDate(v float64) time.Time {
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
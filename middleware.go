@@ -0,0 +1,99 @@
+package rocho
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/teawithsand/rocho/internal"
+)
+
+// NewContext returns copy of context with given AuthToken stored in it.
+func NewContext(ctx context.Context, at AuthToken) context.Context {
+	return context.WithValue(ctx, internal.AuthTokenContextKey, at)
+}
+
+// FromContext returns AuthToken stored in context by SessionMiddleware.
+func FromContext(ctx context.Context) (at AuthToken, ok bool) {
+	at = ctx.Value(internal.AuthTokenContextKey)
+	ok = at != nil
+	return
+}
+
+// MustFromContext returns AuthToken stored in context by SessionMiddleware.
+// It panics if there is no AuthToken, so it should be used only behind middleware in required mode.
+func MustFromContext(ctx context.Context) AuthToken {
+	at, ok := FromContext(ctx)
+	if !ok {
+		panic("rocho: No AuthToken in context")
+	}
+	return at
+}
+
+// SessionMiddleware runs SessionEngine once per request and stores resulting AuthToken in request's context.
+//
+// In optional mode requests without valid AuthToken are passed to next handler as anonymous ones.
+// In required mode UnauthorizedHandler is called for them instead.
+type SessionMiddleware struct {
+	SessionEngine SessionEngine
+	Required      bool
+
+	// UnauthorizedHandler is called in required mode when AuthToken can't be loaded.
+	// Error is ErrNoAuthToken when request has no token at all and InvalidAuthTokenError otherwise.
+	// InvalidAuthTokenError matching ErrInvalidAuthRequest is rendered with 400 by DefaultErrorRenderer.
+	// If nil, DefaultErrorRenderer is used.
+	UnauthorizedHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+	// Challenger sets challenge headers, like WWW-Authenticate, before UnauthorizedHandler is called.
+	// If nil and SessionEngine is *DefaultSessionEngine, its AuthTokenLoader or HTTPAuthTokenDeserializer is used
+	// if it implements AuthChallenger.
+	Challenger AuthChallenger
+}
+
+func (m *SessionMiddleware) challenger() (ac AuthChallenger) {
+	if m.Challenger != nil {
+		ac = m.Challenger
+		return
+	}
+
+	dse, ok := m.SessionEngine.(*DefaultSessionEngine)
+	if !ok {
+		return
+	}
+	if dse.AuthTokenLoader != nil {
+		ac, _ = dse.AuthTokenLoader.(AuthChallenger)
+	} else {
+		ac, _ = dse.HTTPAuthTokenDeserializer.(AuthChallenger)
+	}
+	return
+}
+
+// Wrap creates handler, which loads AuthToken and calls next handler.
+func (m *SessionMiddleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		at, err := m.SessionEngine.GetRequestAuthToken(r.Context(), r)
+		if err != nil || at == nil {
+			if !m.Required {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err == nil {
+				err = ErrNoAuthToken
+			} else if !errors.Is(err, ErrNoAuthToken) {
+				err = &InvalidAuthTokenError{Err: err}
+			}
+			if ac := m.challenger(); ac != nil {
+				ac.Challenge(w.Header(), err)
+			}
+			if m.UnauthorizedHandler != nil {
+				m.UnauthorizedHandler(w, r, err)
+			} else {
+				defaultErrorRenderer.RenderError(w, r, err)
+			}
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), at)))
+	})
+}
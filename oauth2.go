@@ -8,6 +8,9 @@ type OAuth2AuthData struct {
 
 	Config         *oauth2.Config
 	ExchangedToken *oauth2.Token
+
+	// Nonce is OpenID Connect nonce sent with authorization request. It's empty if none was sent.
+	Nonce string
 }
 
 // GetAccessToken returns access token from OAuth2AuthData.
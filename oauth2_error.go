@@ -1,6 +1,9 @@
 package rocho
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 // OAuth2StateError is returned when state from redirect is not equal to state stored in session.
 type OAuth2StateError struct{}
@@ -9,6 +12,13 @@ func (*OAuth2StateError) Error() string {
 	return "rocho: OAuth2 state mismatch"
 }
 
+// ErrNoIDToken is returned when ID token was expected, but token response does not contain it.
+var ErrNoIDToken = errors.New("rocho: No ID token in OAuth2 token response")
+
+// ErrInvalidIDToken is matched by errors returned when ID token fails verification.
+// It's distinct from ErrInvalidCredentials, since user has not supplied any credentials.
+var ErrInvalidIDToken = errors.New("rocho: Invalid ID token")
+
 type OAuth2StateManagerError struct {
 	Err error
 }
@@ -52,3 +62,26 @@ func (err *OAuth2TokenExchangeError) Unwrap() error {
 	}
 	return err.Err
 }
+
+// OAuth2IDTokenError is returned when ID token from OAuth2 flow is missing or fails verification.
+type OAuth2IDTokenError struct {
+	Err error
+}
+
+func (err *OAuth2IDTokenError) Error() string {
+	if err == nil {
+		return "<nil>"
+	}
+
+	if err.Err == nil {
+		return "rocho: OAuth2 ID token error"
+	}
+
+	return fmt.Sprintf("rocho: OAuth2 ID token error: %s", err.Err.Error())
+}
+func (err *OAuth2IDTokenError) Unwrap() error {
+	if err == nil {
+		return nil
+	}
+	return err.Err
+}
@@ -1,6 +1,10 @@
 package rocho
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"net/http"
 
 	"golang.org/x/oauth2"
@@ -9,9 +13,28 @@ import (
 // OAuthStateManager manages OAuth2 state.
 // State is kind of OAuth2 nonce.
 // It's here to protect user from CSRF attacks.
+//
+// CookieStateManager is default implementation.
 type OAuthStateManager interface {
-	InitializeState(w http.ResponseWriter, r *http.Request) (newState string, err error)
-	ReadState(w http.ResponseWriter, r *http.Request) (state string, err error)
+	// InitializeState creates new state and stores it along with given data.
+	InitializeState(w http.ResponseWriter, r *http.Request, data OAuth2StateData) (newState string, err error)
+	// ReadState returns state and data of flow, which request finishes.
+	ReadState(w http.ResponseWriter, r *http.Request) (state string, data OAuth2StateData, err error)
+}
+
+// OAuth2StateData is data of single OAuth2 flow, which is kept by OAuthStateManager alongside state.
+type OAuth2StateData struct {
+	// CodeVerifier is PKCE code verifier. It's empty if PKCE is not used.
+	CodeVerifier string
+	// Nonce is OpenID Connect nonce. It's empty if ID tokens are not verified.
+	Nonce string
+}
+
+// IDTokenVerifier verifies OpenID Connect ID tokens.
+// Implementation is provided by oidc package.
+type IDTokenVerifier interface {
+	// VerifyIDToken verifies ID token. If nonce is not empty, "nonce" claim of token must be equal to it.
+	VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (err error)
 }
 
 // OAuth2Handler uses OAuth2 in order to generate OAuth2AuthData.
@@ -20,6 +43,7 @@ type OAuthStateManager interface {
 // Due to nature of OAuth2, generated auth data is exposed for HTTP handler callback func, rather than by return value.
 type OAuth2Handler struct {
 	StateManager OAuthStateManager
+	// ErrorHandler is called when OAuth2 flow fails. If nil, DefaultErrorRenderer is used.
 	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
 
 	// AuthDataReceiver is responsible for taking AuthData and handling request depending on result.
@@ -29,33 +53,70 @@ type OAuth2Handler struct {
 
 	OAuth2ServiceName string // used to identify OAuth2AuthData generated by this handler.
 	OAuth2Config      *oauth2.Config
+
+	// DisablePKCE turns off PKCE. It should be set only for providers, which reject PKCE parameters.
+	DisablePKCE bool
+
+	// IDTokenVerifier, if set, makes handler send nonce with authorization request
+	// and verify ID token returned from exchange against it before calling AuthDataReceiver.
+	IDTokenVerifier IDTokenVerifier
+
+	// Events receives EventLoginFailed events when OAuth2 flow fails. It may be nil.
+	Events *EventBus
 }
 
-/*
-// move to default implementation
-func generateStateOauthCookie(w http.ResponseWriter) string {
-	var expiration = time.Now().Add(20 * time.Minute)
+func codeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
 
-	b := make([]byte, 16)
-	rand.Read(b)
-	state := base64.URLEncoding.EncodeToString(b)
-	cookie := http.Cookie{Name: "oauthstate", Value: state, Expires: expiration}
-	http.SetCookie(w, &cookie)
+func (handler *OAuth2Handler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	e := newEvent(EventLoginFailed, r, nil, nil, err)
+	e.ProviderName = handler.OAuth2ServiceName
+	handler.Events.Emit(r.Context(), e)
 
-	return state
+	if handler.ErrorHandler != nil {
+		handler.ErrorHandler(w, r, err)
+		return
+	}
+	defaultErrorRenderer.RenderError(w, r, err)
 }
-*/
 
 // InitializeHandler creates handler, which is responsible for initializing OAuth2 flow and redirecting browser to 3rd party website.
 func (handler *OAuth2Handler) InitializeHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		oauthState, err := handler.StateManager.InitializeState(w, r)
+		var data OAuth2StateData
+		var opts []oauth2.AuthCodeOption
+		if !handler.DisablePKCE {
+			verifier, err := generateRandomString()
+			if err != nil {
+				handler.handleError(w, r, err)
+				return
+			}
+			data.CodeVerifier = verifier
+			opts = append(opts,
+				oauth2.SetAuthURLParam("code_challenge", codeChallengeS256(verifier)),
+				oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+			)
+		}
+
+		if handler.IDTokenVerifier != nil {
+			nonce, err := generateRandomString()
+			if err != nil {
+				handler.handleError(w, r, err)
+				return
+			}
+			data.Nonce = nonce
+			opts = append(opts, oauth2.SetAuthURLParam("nonce", nonce))
+		}
+
+		oauthState, err := handler.StateManager.InitializeState(w, r, data)
 		if err != nil {
-			handler.ErrorHandler(w, r, &OAuth2StateManagerError{err})
+			handler.handleError(w, r, &OAuth2StateManagerError{err})
 			return
 		}
 
-		url := handler.OAuth2Config.AuthCodeURL(oauthState)
+		url := handler.OAuth2Config.AuthCodeURL(oauthState, opts...)
 		http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 	})
 }
@@ -67,28 +128,46 @@ func (handler *OAuth2Handler) InitializeHandler() http.Handler {
 // In order to replace HTTP client used by default replace context and it's value: oauth2.HTTPClient in HTTP request using middleware.
 func (handler *OAuth2Handler) CallbackHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		oauthState, err := handler.StateManager.ReadState(w, r)
+		oauthState, data, err := handler.StateManager.ReadState(w, r)
 		if err != nil {
-			handler.ErrorHandler(w, r, &OAuth2StateManagerError{err})
+			handler.handleError(w, r, &OAuth2StateManagerError{err})
 			return
 		}
 
-		// TODO(teawtihsand): constant time compare here?
-		if r.FormValue("state") != oauthState {
-			handler.ErrorHandler(w, r, &OAuth2StateError{})
+		if subtle.ConstantTimeCompare([]byte(r.FormValue("state")), []byte(oauthState)) != 1 {
+			handler.handleError(w, r, &OAuth2StateError{})
 			return
 		}
 
-		token, err := handler.OAuth2Config.Exchange(r.Context(), r.FormValue("code"))
+		var opts []oauth2.AuthCodeOption
+		if data.CodeVerifier != "" {
+			opts = append(opts, oauth2.SetAuthURLParam("code_verifier", data.CodeVerifier))
+		}
+
+		token, err := handler.OAuth2Config.Exchange(r.Context(), r.FormValue("code"), opts...)
 		if err != nil {
-			handler.ErrorHandler(w, r, &OAuth2TokenExchangeError{err})
+			handler.handleError(w, r, &OAuth2TokenExchangeError{err})
 			return
 		}
 
+		if handler.IDTokenVerifier != nil {
+			rawIDToken, _ := token.Extra("id_token").(string)
+			if rawIDToken == "" {
+				handler.handleError(w, r, &OAuth2IDTokenError{ErrNoIDToken})
+				return
+			}
+			err = handler.IDTokenVerifier.VerifyIDToken(r.Context(), rawIDToken, data.Nonce)
+			if err != nil {
+				handler.handleError(w, r, &OAuth2IDTokenError{err})
+				return
+			}
+		}
+
 		ad := OAuth2AuthData{
 			Config:            handler.OAuth2Config,
 			ExchangedToken:    token,
 			OAuth2ServiceName: handler.OAuth2ServiceName,
+			Nonce:             data.Nonce,
 		}
 
 		handler.AuthDataReceiver(w, r, ad)
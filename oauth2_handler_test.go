@@ -0,0 +1,247 @@
+package rocho
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// testTokenEndpoint is OAuth2 token endpoint, which records exchange requests.
+type testTokenEndpoint struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	requests []url.Values
+	idToken  string
+}
+
+func newTestTokenEndpoint() *testTokenEndpoint {
+	te := &testTokenEndpoint{}
+	te.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		te.lock.Lock()
+		te.requests = append(te.requests, r.PostForm)
+		res := map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+		}
+		if te.idToken != "" {
+			res["id_token"] = te.idToken
+		}
+		te.lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	return te
+}
+
+func (te *testTokenEndpoint) lastRequest() url.Values {
+	te.lock.Lock()
+	defer te.lock.Unlock()
+	if len(te.requests) == 0 {
+		return nil
+	}
+	return te.requests[len(te.requests)-1]
+}
+
+func newTestOAuth2Handler(te *testTokenEndpoint) *OAuth2Handler {
+	now := time.Now()
+	return &OAuth2Handler{
+		StateManager:      newTestStateManager(&now),
+		OAuth2ServiceName: "test",
+		OAuth2Config: &oauth2.Config{
+			ClientID:     "client",
+			ClientSecret: "secret",
+			RedirectURL:  "https://app.example.com/callback",
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   "https://provider.example.com/auth",
+				TokenURL:  te.URL,
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+	}
+}
+
+// runOAuth2Flow runs initialization and callback of handler and returns authorization URL and either AuthData
+// received by handler or error it failed with.
+func runOAuth2Flow(t *testing.T, handler *OAuth2Handler) (authURL *url.URL, ad *OAuth2AuthData, err error) {
+	t.Helper()
+
+	handler.AuthDataReceiver = func(w http.ResponseWriter, r *http.Request, received AuthData) {
+		oad := received.(OAuth2AuthData)
+		ad = &oad
+	}
+	handler.ErrorHandler = func(w http.ResponseWriter, r *http.Request, handlerErr error) {
+		err = handlerErr
+	}
+
+	w := httptest.NewRecorder()
+	handler.InitializeHandler().ServeHTTP(w, httptest.NewRequest("GET", "/login", nil))
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("expected redirect, got %d", w.Code)
+	}
+	authURL, parseErr := url.Parse(w.Header().Get("Location"))
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	r := callbackRequest(authURL.Query().Get("state"), w.Result().Cookies()...)
+	w = httptest.NewRecorder()
+	handler.CallbackHandler().ServeHTTP(w, r)
+	return
+}
+
+func TestOAuth2Handler_PKCE(t *testing.T) {
+	te := newTestTokenEndpoint()
+	defer te.Close()
+
+	authURL, ad, err := runOAuth2Flow(t, newTestOAuth2Handler(te))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ad.ExchangedToken.AccessToken != "access" || ad.OAuth2ServiceName != "test" {
+		t.Errorf("unexpected auth data %+v", ad)
+	}
+
+	verifier := te.lastRequest().Get("code_verifier")
+	if verifier == "" {
+		t.Fatal("expected code verifier to be sent with token exchange")
+	}
+	q := authURL.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") != codeChallengeS256(verifier) {
+		t.Errorf("expected code challenge to be S256 of verifier, got %q", q.Get("code_challenge"))
+	}
+}
+
+// RFC 7636 appendix B
+func TestCodeChallengeS256(t *testing.T) {
+	challenge := codeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if challenge != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("unexpected challenge %q", challenge)
+	}
+}
+
+func TestOAuth2Handler_DisablePKCE(t *testing.T) {
+	te := newTestTokenEndpoint()
+	defer te.Close()
+
+	handler := newTestOAuth2Handler(te)
+	handler.DisablePKCE = true
+	authURL, _, err := runOAuth2Flow(t, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := authURL.Query()
+	if _, ok := q["code_challenge"]; ok {
+		t.Error("expected no code challenge")
+	}
+	if _, ok := q["code_challenge_method"]; ok {
+		t.Error("expected no code challenge method")
+	}
+	if _, ok := te.lastRequest()["code_verifier"]; ok {
+		t.Error("expected no code verifier in token exchange")
+	}
+}
+
+// testIDTokenVerifier records nonces it was called with.
+type testIDTokenVerifier struct {
+	nonces []string
+	err    error
+}
+
+func (v *testIDTokenVerifier) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (err error) {
+	v.nonces = append(v.nonces, nonce)
+	err = v.err
+	return
+}
+
+func TestOAuth2Handler_Nonce(t *testing.T) {
+	te := newTestTokenEndpoint()
+	defer te.Close()
+	te.idToken = "id token"
+
+	verifier := &testIDTokenVerifier{}
+	handler := newTestOAuth2Handler(te)
+	handler.IDTokenVerifier = verifier
+	authURL, ad, err := runOAuth2Flow(t, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := authURL.Query().Get("nonce")
+	if nonce == "" {
+		t.Fatal("expected nonce to be sent with authorization request")
+	}
+	if len(verifier.nonces) != 1 || verifier.nonces[0] != nonce {
+		t.Errorf("expected ID token to be verified against %q, got %v", nonce, verifier.nonces)
+	}
+	if ad.Nonce != nonce {
+		t.Errorf("expected nonce %q in auth data, got %q", nonce, ad.Nonce)
+	}
+
+	// each flow has its own nonce
+	authURL, _, err = runOAuth2Flow(t, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authURL.Query().Get("nonce") == nonce {
+		t.Error("expected nonce to be generated for each flow")
+	}
+}
+
+func TestOAuth2Handler_NonceRejected(t *testing.T) {
+	te := newTestTokenEndpoint()
+	defer te.Close()
+
+	errMismatch := errors.New("nonce mismatch")
+	for name, tc := range map[string]struct {
+		idToken string
+		err     error
+	}{
+		"no ID token":       {err: ErrNoIDToken},
+		"verifier rejected": {idToken: "id token", err: errMismatch},
+	} {
+		t.Run(name, func(t *testing.T) {
+			te.idToken = tc.idToken
+
+			handler := newTestOAuth2Handler(te)
+			handler.IDTokenVerifier = &testIDTokenVerifier{err: errMismatch}
+			_, ad, err := runOAuth2Flow(t, handler)
+			var iderr *OAuth2IDTokenError
+			if !errors.As(err, &iderr) || !errors.Is(err, tc.err) {
+				t.Errorf("expected OAuth2IDTokenError with %v, got %v", tc.err, err)
+			}
+			if ad != nil {
+				t.Error("expected AuthDataReceiver not to be called")
+			}
+		})
+	}
+}
+
+func TestOAuth2Handler_NoNonceWithoutVerifier(t *testing.T) {
+	te := newTestTokenEndpoint()
+	defer te.Close()
+
+	authURL, ad, err := runOAuth2Flow(t, newTestOAuth2Handler(te))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := authURL.Query()["nonce"]; ok || ad.Nonce != "" {
+		t.Errorf("expected no nonce, got %q", ad.Nonce)
+	}
+}
@@ -0,0 +1,166 @@
+package rocho
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const defaultOAuth2RegistryPrefix = "/auth"
+
+// OAuth2Provider is single OAuth2 provider registered in OAuth2Registry.
+// It's identified by OAuth2ServiceName of it's handler.
+type OAuth2Provider struct {
+	Handler *OAuth2Handler
+	// UserDataProvider fetches user data from OAuth2AuthData created by Handler.
+	UserDataProvider UserDataProvider
+
+	// DisplayName is name of provider shown to user, for instance on login button.
+	DisplayName string
+	// Disabled providers are not listed, not routed and their OAuth2AuthData is not accepted.
+	Disabled bool
+}
+
+// Name returns name of provider, which is OAuth2ServiceName of it's handler.
+func (p *OAuth2Provider) Name() string {
+	return p.Handler.OAuth2ServiceName
+}
+
+// OAuth2ProviderInfo describes enabled provider. It's intended for rendering login buttons.
+type OAuth2ProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+}
+
+// OAuth2Registry holds many OAuth2 providers and serves their flows using single http.Handler.
+//
+// Redirect URL of provider's OAuth2Config should point to it's callback route.
+// It serves following routes:
+//
+//	{Prefix}/{provider}/login - starts flow, see OAuth2Handler.InitializeHandler
+//	{Prefix}/{provider}/callback - finishes flow, see OAuth2Handler.CallbackHandler
+//
+// Both routes accept only GET, so providers must use default "query" response mode.
+//
+// It's also UserDataProvider, which passes OAuth2AuthData to UserDataProvider of provider, which has created it.
+type OAuth2Registry struct {
+	Providers []*OAuth2Provider
+
+	// Prefix is path prefix of routes. If empty, "/auth" is used.
+	Prefix string
+	// NotFoundHandler handles requests to unknown or disabled providers. If nil, http.NotFound is used.
+	NotFoundHandler http.Handler
+}
+
+func (reg *OAuth2Registry) prefix() string {
+	if reg.Prefix == "" {
+		return defaultOAuth2RegistryPrefix
+	}
+	return strings.TrimSuffix(reg.Prefix, "/")
+}
+
+// Provider returns enabled provider with given name or nil if there is no such provider.
+func (reg *OAuth2Registry) Provider(name string) *OAuth2Provider {
+	for _, p := range reg.Providers {
+		if !p.Disabled && p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// EnabledProviders lists enabled providers in order they were registered.
+func (reg *OAuth2Registry) EnabledProviders() (res []OAuth2ProviderInfo) {
+	for _, p := range reg.Providers {
+		if p.Disabled {
+			continue
+		}
+
+		displayName := p.DisplayName
+		if displayName == "" {
+			displayName = p.Name()
+		}
+		res = append(res, OAuth2ProviderInfo{
+			Name:        p.Name(),
+			DisplayName: displayName,
+			LoginURL:    reg.prefix() + "/" + p.Name() + "/login",
+		})
+	}
+	return
+}
+
+func (reg *OAuth2Registry) notFound(w http.ResponseWriter, r *http.Request) {
+	if reg.NotFoundHandler != nil {
+		reg.NotFoundHandler.ServeHTTP(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func (reg *OAuth2Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, reg.prefix()+"/")
+	if rest == r.URL.Path {
+		reg.notFound(w, r)
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 {
+		reg.notFound(w, r)
+		return
+	}
+	p := reg.Provider(parts[0])
+	if p == nil {
+		reg.notFound(w, r)
+		return
+	}
+
+	switch parts[1] {
+	case "login":
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		p.Handler.InitializeHandler().ServeHTTP(w, r)
+	case "callback":
+		// "form_post" response mode is not supported, since SameSite=Lax state cookie
+		// is not sent with cross-site POST from provider.
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		p.Handler.CallbackHandler().ServeHTTP(w, r)
+	default:
+		reg.notFound(w, r)
+	}
+}
+
+// GetUserData passes OAuth2AuthData to UserDataProvider of provider with matching OAuth2ServiceName.
+// Provider gets *OAuth2AuthData, so it's usable as TokenAuthData.
+//
+// It returns ErrAuthDataNotSupported for other AuthData and OAuth2AuthData of unknown or disabled providers.
+func (reg *OAuth2Registry) GetUserData(ctx context.Context, ad AuthData) (ud UserData, err error) {
+	var oad *OAuth2AuthData
+	switch v := ad.(type) {
+	case OAuth2AuthData:
+		oad = &v
+	case *OAuth2AuthData:
+		oad = v
+	}
+	if oad == nil {
+		err = ErrAuthDataNotSupported
+		return
+	}
+
+	p := reg.Provider(oad.OAuth2ServiceName)
+	if p == nil || p.UserDataProvider == nil {
+		err = ErrAuthDataNotSupported
+		return
+	}
+
+	ud, err = p.UserDataProvider.GetUserData(ctx, oad)
+	return
+}
@@ -0,0 +1,179 @@
+package rocho
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// namedUserDataProvider returns it's name as user data.
+type namedUserDataProvider string
+
+func (p namedUserDataProvider) GetUserData(ctx context.Context, ad AuthData) (ud UserData, err error) {
+	if _, ok := ad.(*OAuth2AuthData); !ok {
+		err = ErrAuthDataNotSupported
+		return
+	}
+	ud = string(p)
+	return
+}
+
+// newTestRegistry creates registry with providers "first", "second" and disabled "disabled".
+// Callback errors are recorded with name of provider, which handled them.
+func newTestRegistry(te *testTokenEndpoint, failedProviders *[]string) *OAuth2Registry {
+	reg := &OAuth2Registry{}
+	for _, name := range []string{"first", "second", "disabled"} {
+		name := name
+		handler := newTestOAuth2Handler(te)
+		handler.OAuth2ServiceName = name
+		handler.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			*failedProviders = append(*failedProviders, name)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		reg.Providers = append(reg.Providers, &OAuth2Provider{
+			Handler:          handler,
+			UserDataProvider: namedUserDataProvider(name),
+			DisplayName:      strings.ToUpper(name),
+			Disabled:         name == "disabled",
+		})
+	}
+	return reg
+}
+
+func TestOAuth2Registry_Routing(t *testing.T) {
+	te := newTestTokenEndpoint()
+	defer te.Close()
+
+	for name, tc := range map[string]struct {
+		method, path string
+		prefix       string
+		code         int
+		allow        string
+		failed       []string
+	}{
+		"login":                    {method: "GET", path: "/auth/first/login", code: http.StatusTemporaryRedirect},
+		"login with custom prefix": {method: "GET", path: "/oauth/first/login", prefix: "/oauth/", code: http.StatusTemporaryRedirect},
+		"login with POST":          {method: "POST", path: "/auth/first/login", code: http.StatusMethodNotAllowed, allow: "GET"},
+		"callback":                 {method: "GET", path: "/auth/second/callback", code: http.StatusBadRequest, failed: []string{"second"}},
+		"callback with POST":       {method: "POST", path: "/auth/second/callback", code: http.StatusMethodNotAllowed, allow: "GET"},
+		"unknown provider":         {method: "GET", path: "/auth/third/login", code: http.StatusNotFound},
+		"disabled provider":        {method: "GET", path: "/auth/disabled/login", code: http.StatusNotFound},
+		"unknown route":            {method: "GET", path: "/auth/first/logout", code: http.StatusNotFound},
+		"nested route":             {method: "GET", path: "/auth/first/login/more", code: http.StatusNotFound},
+		"no route":                 {method: "GET", path: "/auth/first", code: http.StatusNotFound},
+		"other prefix":             {method: "GET", path: "/other/first/login", code: http.StatusNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var failed []string
+			reg := newTestRegistry(te, &failed)
+			reg.Prefix = tc.prefix
+
+			w := httptest.NewRecorder()
+			reg.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+			if w.Code != tc.code || w.Header().Get("Allow") != tc.allow {
+				t.Errorf("unexpected response %d with Allow %q", w.Code, w.Header().Get("Allow"))
+			}
+			if !reflect.DeepEqual(failed, tc.failed) {
+				t.Errorf("expected callback to be handled by %v, got %v", tc.failed, failed)
+			}
+		})
+	}
+}
+
+func TestOAuth2Registry_NotFoundHandler(t *testing.T) {
+	te := newTestTokenEndpoint()
+	defer te.Close()
+	var failed []string
+	reg := newTestRegistry(te, &failed)
+	reg.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	w := httptest.NewRecorder()
+	reg.ServeHTTP(w, httptest.NewRequest("GET", "/auth/third/login", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("expected NotFoundHandler to be used, got %d", w.Code)
+	}
+}
+
+func TestOAuth2Registry_Flow(t *testing.T) {
+	te := newTestTokenEndpoint()
+	defer te.Close()
+	var failed []string
+	reg := newTestRegistry(te, &failed)
+
+	var ad AuthData
+	reg.Provider("second").Handler.AuthDataReceiver = func(w http.ResponseWriter, r *http.Request, received AuthData) {
+		ad = received
+	}
+
+	w := httptest.NewRecorder()
+	reg.ServeHTTP(w, httptest.NewRequest("GET", "/auth/second/login", nil))
+	location, err := w.Result().Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := callbackRequest(location.Query().Get("state"), w.Result().Cookies()...)
+	r.URL.Path = "/auth/second/callback"
+	reg.ServeHTTP(httptest.NewRecorder(), r)
+	if len(failed) != 0 {
+		t.Fatalf("expected flow to succeed, failed in %v", failed)
+	}
+
+	ud, err := reg.GetUserData(context.Background(), ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ud != "second" {
+		t.Errorf("expected auth data to be routed to second provider, got %v", ud)
+	}
+}
+
+func TestOAuth2Registry_GetUserData(t *testing.T) {
+	te := newTestTokenEndpoint()
+	defer te.Close()
+	var failed []string
+	reg := newTestRegistry(te, &failed)
+	reg.Providers = append(reg.Providers, &OAuth2Provider{Handler: &OAuth2Handler{OAuth2ServiceName: "no provider"}})
+
+	for name, tc := range map[string]struct {
+		ad  AuthData
+		ud  UserData
+		err error
+	}{
+		"value":                  {ad: OAuth2AuthData{OAuth2ServiceName: "first"}, ud: "first"},
+		"pointer":                {ad: &OAuth2AuthData{OAuth2ServiceName: "second"}, ud: "second"},
+		"unknown provider":       {ad: OAuth2AuthData{OAuth2ServiceName: "third"}, err: ErrAuthDataNotSupported},
+		"disabled provider":      {ad: OAuth2AuthData{OAuth2ServiceName: "disabled"}, err: ErrAuthDataNotSupported},
+		"no UserDataProvider":    {ad: OAuth2AuthData{OAuth2ServiceName: "no provider"}, err: ErrAuthDataNotSupported},
+		"other kind of AuthData": {ad: "other", err: ErrAuthDataNotSupported},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ud, err := reg.GetUserData(context.Background(), tc.ad)
+			if !errors.Is(err, tc.err) || ud != tc.ud {
+				t.Errorf("expected %v and %v, got %v and %v", tc.ud, tc.err, ud, err)
+			}
+		})
+	}
+}
+
+func TestOAuth2Registry_EnabledProviders(t *testing.T) {
+	te := newTestTokenEndpoint()
+	defer te.Close()
+	var failed []string
+	reg := newTestRegistry(te, &failed)
+	reg.Providers[1].DisplayName = ""
+
+	expected := []OAuth2ProviderInfo{
+		{Name: "first", DisplayName: "FIRST", LoginURL: "/auth/first/login"},
+		{Name: "second", DisplayName: "second", LoginURL: "/auth/second/login"},
+	}
+	if providers := reg.EnabledProviders(); !reflect.DeepEqual(providers, expected) {
+		t.Errorf("unexpected providers %+v", providers)
+	}
+}
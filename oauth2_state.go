@@ -0,0 +1,226 @@
+package rocho
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrOAuth2StateNotFound is returned by OAuthStateManager when there is no state stored for request.
+var ErrOAuth2StateNotFound = errors.New("rocho: OAuth2 state not found")
+
+// ErrInvalidOAuth2State is returned by OAuthStateManager when stored state is malformed, forged or expired.
+var ErrInvalidOAuth2State = errors.New("rocho: Invalid OAuth2 state")
+
+// ErrOAuth2StateKeyNotSet is returned by CookieStateManager when it has no key.
+var ErrOAuth2StateKeyNotSet = errors.New("rocho: OAuth2 state key is not set")
+
+const (
+	defaultStateCookieName = "rocho_oauth_state"
+	defaultStateTTL        = 10 * time.Minute
+	randomStringSize       = 32
+)
+
+var stateEncoding = base64.RawURLEncoding
+
+// CookieStateManager is OAuthStateManager, which keeps state in signed cookies.
+//
+// Each flow gets it's own cookie, which name is derived from state,
+// so user may run multiple flows concurrently, for instance in different tabs.
+// Cookie is removed once state is read.
+//
+// Cookies are always HttpOnly. They are Secure and SameSite=Lax unless configured otherwise.
+// SameSite must not be Strict, since browser would not send cookie on redirect from provider.
+type CookieStateManager struct {
+	// Key is used for signing cookies with HMAC-SHA256. It should be at least 32 random bytes.
+	Key []byte
+
+	Name string // prefix of cookie names; if empty, "rocho_oauth_state" is used
+	// Path should be path of callback, so cookie is not sent anywhere else.
+	Path   string
+	Domain string
+
+	// Insecure disables Secure flag. It should be used only for development over plain HTTP.
+	Insecure bool
+	// SameSite defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+
+	// TTL is time given to user for finishing flow. If zero, ten minutes are used.
+	TTL time.Duration
+
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+type stateCookiePayload struct {
+	State        string `json:"s"`
+	ExpiresAt    int64  `json:"e"`
+	CodeVerifier string `json:"v,omitempty"`
+	Nonce        string `json:"n,omitempty"`
+}
+
+// generateRandomString generates random string with 256 bits of entropy, which is safe to use in URLs.
+func generateRandomString() (s string, err error) {
+	raw := make([]byte, randomStringSize)
+	_, err = rand.Read(raw)
+	if err != nil {
+		return
+	}
+	s = stateEncoding.EncodeToString(raw)
+	return
+}
+
+func (sm *CookieStateManager) now() time.Time {
+	if sm.Now != nil {
+		return sm.Now()
+	}
+	return time.Now()
+}
+
+func (sm *CookieStateManager) cookieName(state string) string {
+	name := sm.Name
+	if name == "" {
+		name = defaultStateCookieName
+	}
+	sum := sha256.Sum256([]byte(state))
+	return name + "_" + hex.EncodeToString(sum[:8])
+}
+
+func (sm *CookieStateManager) newCookie(name, value string, ttl time.Duration) *http.Cookie {
+	sameSite := sm.SameSite
+	if sameSite == 0 {
+		sameSite = http.SameSiteLaxMode
+	}
+	path := sm.Path
+	if path == "" {
+		path = "/"
+	}
+
+	c := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   sm.Domain,
+		Secure:   !sm.Insecure,
+		HttpOnly: true,
+		SameSite: sameSite,
+	}
+	if ttl <= 0 {
+		c.MaxAge = -1
+		c.Expires = time.Unix(1, 0)
+	} else {
+		c.MaxAge = int(ttl / time.Second)
+		c.Expires = sm.now().Add(ttl)
+	}
+	return c
+}
+
+func (sm *CookieStateManager) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, sm.Key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// InitializeState generates new random state and stores it in cookie along with given data.
+func (sm *CookieStateManager) InitializeState(w http.ResponseWriter, r *http.Request, data OAuth2StateData) (newState string, err error) {
+	if len(sm.Key) == 0 {
+		err = ErrOAuth2StateKeyNotSet
+		return
+	}
+
+	state, err := generateRandomString()
+	if err != nil {
+		return
+	}
+
+	ttl := sm.TTL
+	if ttl <= 0 {
+		ttl = defaultStateTTL
+	}
+	expiresAt := sm.now().Add(ttl)
+
+	payload, err := json.Marshal(stateCookiePayload{
+		State:        state,
+		ExpiresAt:    expiresAt.Unix(),
+		CodeVerifier: data.CodeVerifier,
+		Nonce:        data.Nonce,
+	})
+	if err != nil {
+		return
+	}
+
+	value := stateEncoding.EncodeToString(payload) + "." + stateEncoding.EncodeToString(sm.sign(payload))
+	http.SetCookie(w, sm.newCookie(sm.cookieName(state), value, ttl))
+
+	newState = state
+	return
+}
+
+// ReadState reads state of flow, which state is passed in "state" parameter of request, and removes it's cookie.
+func (sm *CookieStateManager) ReadState(w http.ResponseWriter, r *http.Request) (state string, data OAuth2StateData, err error) {
+	if len(sm.Key) == 0 {
+		err = ErrOAuth2StateKeyNotSet
+		return
+	}
+
+	requestState := r.FormValue("state")
+	if requestState == "" {
+		err = ErrOAuth2StateNotFound
+		return
+	}
+
+	name := sm.cookieName(requestState)
+	c, err := r.Cookie(name)
+	if errors.Is(err, http.ErrNoCookie) {
+		err = ErrOAuth2StateNotFound
+		return
+	} else if err != nil {
+		return
+	}
+	http.SetCookie(w, sm.newCookie(name, "", 0))
+
+	parts := strings.Split(c.Value, ".")
+	if len(parts) != 2 {
+		err = ErrInvalidOAuth2State
+		return
+	}
+	payload, err := stateEncoding.DecodeString(parts[0])
+	if err != nil {
+		err = ErrInvalidOAuth2State
+		return
+	}
+	signature, err := stateEncoding.DecodeString(parts[1])
+	if err != nil {
+		err = ErrInvalidOAuth2State
+		return
+	}
+	if !hmac.Equal(signature, sm.sign(payload)) {
+		err = ErrInvalidOAuth2State
+		return
+	}
+
+	var p stateCookiePayload
+	err = json.Unmarshal(payload, &p)
+	if err != nil {
+		err = ErrInvalidOAuth2State
+		return
+	}
+	if !sm.now().Before(time.Unix(p.ExpiresAt, 0)) {
+		err = ErrInvalidOAuth2State
+		return
+	}
+
+	state = p.State
+	data = OAuth2StateData{
+		CodeVerifier: p.CodeVerifier,
+		Nonce:        p.Nonce,
+	}
+	return
+}
@@ -0,0 +1,190 @@
+package rocho
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStateManager(now *time.Time) *CookieStateManager {
+	return &CookieStateManager{
+		Key:  []byte("0123456789abcdef0123456789abcdef"),
+		Path: "/callback",
+		Now: func() time.Time {
+			return *now
+		},
+	}
+}
+
+func initState(t *testing.T, sm OAuthStateManager, data OAuth2StateData) (state string, cookie *http.Cookie) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	state, err := sm.InitializeState(w, httptest.NewRequest("GET", "/login", nil), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected single cookie, got %d", len(cookies))
+	}
+	cookie = cookies[0]
+	return
+}
+
+func callbackRequest(state string, cookies ...*http.Cookie) *http.Request {
+	r := httptest.NewRequest("GET", "/callback?"+url.Values{"state": {state}, "code": {"code"}}.Encode(), nil)
+	for _, c := range cookies {
+		r.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return r
+}
+
+func validCallback(state string, cookie *http.Cookie) *http.Request {
+	return callbackRequest(state, cookie)
+}
+
+func TestCookieStateManager_RoundTrip(t *testing.T) {
+	now := time.Now()
+	sm := newTestStateManager(&now)
+	data := OAuth2StateData{CodeVerifier: "verifier", Nonce: "nonce"}
+
+	state, cookie := initState(t, sm, data)
+	if !strings.HasPrefix(cookie.Name, defaultStateCookieName+"_") || cookie.Path != "/callback" ||
+		!cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode || cookie.MaxAge != 600 {
+		t.Errorf("unexpected cookie %+v", cookie)
+	}
+	if strings.Contains(cookie.Value, "verifier") {
+		t.Error("expected cookie value to be encoded")
+	}
+
+	w := httptest.NewRecorder()
+	readState, readData, err := sm.ReadState(w, callbackRequest(state, cookie))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readState != state || readData != data {
+		t.Errorf("unexpected state %q and data %+v", readState, readData)
+	}
+
+	cleared := w.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].Name != cookie.Name || cleared[0].MaxAge >= 0 || cleared[0].Value != "" {
+		t.Errorf("expected cookie to be cleared on callback, got %+v", cleared)
+	}
+}
+
+func TestCookieStateManager_ConcurrentFlows(t *testing.T) {
+	now := time.Now()
+	sm := newTestStateManager(&now)
+
+	state1, cookie1 := initState(t, sm, OAuth2StateData{Nonce: "first"})
+	state2, cookie2 := initState(t, sm, OAuth2StateData{Nonce: "second"})
+	if cookie1.Name == cookie2.Name {
+		t.Fatal("expected each flow to have its own cookie")
+	}
+
+	for state, nonce := range map[string]string{state1: "first", state2: "second"} {
+		readState, data, err := sm.ReadState(httptest.NewRecorder(), callbackRequest(state, cookie1, cookie2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if readState != state || data.Nonce != nonce {
+			t.Errorf("expected flow %q with nonce %q, got %q with %+v", state, nonce, readState, data)
+		}
+	}
+}
+
+func TestCookieStateManager_Rejects(t *testing.T) {
+	now := time.Now()
+	sm := newTestStateManager(&now)
+
+	for name, tc := range map[string]struct {
+		request func(state string, cookie *http.Cookie) *http.Request
+		sm      func(sm *CookieStateManager)
+		err     error
+	}{
+		"no state parameter": {
+			request: func(state string, cookie *http.Cookie) *http.Request {
+				return callbackRequest("", cookie)
+			},
+			err: ErrOAuth2StateNotFound,
+		},
+		"no cookie": {
+			request: func(state string, cookie *http.Cookie) *http.Request {
+				return callbackRequest(state)
+			},
+			err: ErrOAuth2StateNotFound,
+		},
+		"other flow's state": {
+			request: func(state string, cookie *http.Cookie) *http.Request {
+				return callbackRequest("other", cookie)
+			},
+			err: ErrOAuth2StateNotFound,
+		},
+		"tampered payload": {
+			request: func(state string, cookie *http.Cookie) *http.Request {
+				parts := strings.Split(cookie.Value, ".")
+				payload, _ := stateEncoding.DecodeString(parts[0])
+				payload = []byte(strings.Replace(string(payload), `"n":"nonce"`, `"n":"forged"`, 1))
+				cookie.Value = stateEncoding.EncodeToString(payload) + "." + parts[1]
+				return callbackRequest(state, cookie)
+			},
+			err: ErrInvalidOAuth2State,
+		},
+		"tampered signature": {
+			request: func(state string, cookie *http.Cookie) *http.Request {
+				cookie.Value = cookie.Value[:strings.IndexByte(cookie.Value, '.')+1] + stateEncoding.EncodeToString(make([]byte, 32))
+				return callbackRequest(state, cookie)
+			},
+			err: ErrInvalidOAuth2State,
+		},
+		"malformed": {
+			request: func(state string, cookie *http.Cookie) *http.Request {
+				cookie.Value = "garbage"
+				return callbackRequest(state, cookie)
+			},
+			err: ErrInvalidOAuth2State,
+		},
+		"other key": {
+			request: validCallback,
+			sm: func(sm *CookieStateManager) {
+				sm.Key = []byte("other key")
+			},
+			err: ErrInvalidOAuth2State,
+		},
+		"expired": {
+			request: validCallback,
+			sm: func(sm *CookieStateManager) {
+				later := now.Add(defaultStateTTL)
+				sm.Now = func() time.Time {
+					return later
+				}
+			},
+			err: ErrInvalidOAuth2State,
+		},
+		"no key": {
+			request: validCallback,
+			sm: func(sm *CookieStateManager) {
+				sm.Key = nil
+			},
+			err: ErrOAuth2StateKeyNotSet,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			state, cookie := initState(t, sm, OAuth2StateData{Nonce: "nonce"})
+
+			reader := newTestStateManager(&now)
+			if tc.sm != nil {
+				tc.sm(reader)
+			}
+			_, _, err := reader.ReadState(httptest.NewRecorder(), tc.request(state, cookie))
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package oidc
+
+import "github.com/teawithsand/rocho/jwt"
+
+// Claims contains claims of ID token, including standard ones from OpenID Connect Core 1.0.
+type Claims struct {
+	jwt.StandardClaims
+
+	Nonce           string `json:"nonce,omitempty"`
+	AuthorizedParty string `json:"azp,omitempty"`
+	AuthTime        int64  `json:"auth_time,omitempty"`
+
+	Email             string `json:"email,omitempty"`
+	EmailVerified     bool   `json:"email_verified,omitempty"`
+	Name              string `json:"name,omitempty"`
+	GivenName         string `json:"given_name,omitempty"`
+	FamilyName        string `json:"family_name,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Picture           string `json:"picture,omitempty"`
+	Locale            string `json:"locale,omitempty"`
+}
+
+// UserInfo is rocho.UserData returned by Provider.
+type UserInfo struct {
+	Provider string `json:"provider"`
+	Claims
+}
+
+// ProviderName returns name of provider, which has issued ID token.
+func (ui *UserInfo) ProviderName() string {
+	return ui.Provider
+}
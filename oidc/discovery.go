@@ -0,0 +1,95 @@
+// Package oidc implements OpenID Connect on top of rocho's OAuth2 flow:
+// provider discovery, JWKS handling, ID token verification and rocho.UserDataProvider returning standard claims.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+const maxDocumentSize = 1024 * 1024
+
+// Metadata is OpenID provider metadata obtained from discovery document.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint,omitempty"`
+	JWKSURI               string `json:"jwks_uri"`
+
+	ScopesSupported                  []string `json:"scopes_supported,omitempty"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported,omitempty"`
+}
+
+// Endpoint returns OAuth2 endpoint of provider.
+func (m *Metadata) Endpoint() oauth2.Endpoint {
+	return oauth2.Endpoint{
+		AuthURL:  m.AuthorizationEndpoint,
+		TokenURL: m.TokenEndpoint,
+	}
+}
+
+// Discover fetches metadata of provider from issuer's "/.well-known/openid-configuration".
+// Issuer from document must be equal to given one.
+//
+// HTTP client can be replaced by setting oauth2.HTTPClient value of context.
+func Discover(ctx context.Context, issuer string) (m *Metadata, err error) {
+	u := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	md := &Metadata{}
+	err = fetchJSON(ctx, u, md)
+	if err != nil {
+		return
+	}
+	if md.Issuer != issuer {
+		err = ErrIssuerMismatch
+		return
+	}
+
+	m = md
+	return
+}
+
+func fetchJSON(ctx context.Context, u string, v interface{}) (err error) {
+	defer func() {
+		if err != nil {
+			err = &FetchError{URL: u, Err: err}
+		}
+	}()
+
+	request, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := getHTTPClient(ctx).Do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		err = errors.New("rocho/oidc: Non 200 HTTP response")
+		return
+	}
+
+	err = json.NewDecoder(io.LimitReader(response.Body, maxDocumentSize)).Decode(v)
+	return
+}
+
+func getHTTPClient(ctx context.Context) (client *http.Client) {
+	rawCl := ctx.Value(oauth2.HTTPClient)
+	if rawCl != nil {
+		client = rawCl.(*http.Client)
+		return
+	}
+	client = http.DefaultClient
+	return
+}
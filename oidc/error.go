@@ -0,0 +1,70 @@
+package oidc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/teawithsand/rocho"
+)
+
+// ErrIssuerMismatch is returned when discovery document describes issuer other than requested one.
+var ErrIssuerMismatch = errors.New("rocho/oidc: Issuer in discovery document does not match")
+
+// ErrMissingClaim is returned when ID token lacks required claim.
+var ErrMissingClaim = errors.New("rocho/oidc: ID token lacks required claim")
+
+// ErrInvalidNonce is returned when "nonce" claim of ID token does not match expected one.
+var ErrInvalidNonce = errors.New("rocho/oidc: Invalid nonce")
+
+// ErrInvalidAuthorizedParty is returned when "azp" claim of ID token is not client's ID.
+var ErrInvalidAuthorizedParty = errors.New("rocho/oidc: Invalid authorized party")
+
+// FetchError is returned when document can't be fetched from issuer.
+type FetchError struct {
+	URL string
+	Err error
+}
+
+func (err *FetchError) Error() string {
+	if err == nil {
+		return "<nil>"
+	}
+	if err.Err == nil {
+		return fmt.Sprintf("rocho/oidc: Can't fetch %q", err.URL)
+	}
+	return fmt.Sprintf("rocho/oidc: Can't fetch %q: %s", err.URL, err.Err.Error())
+}
+func (err *FetchError) Unwrap() error {
+	if err == nil {
+		return nil
+	}
+	return err.Err
+}
+
+// InvalidIDTokenError is returned by Provider when ID token fails verification.
+// It matches rocho.ErrInvalidIDToken, but not rocho.ErrInvalidCredentials,
+// since it's caused by identity provider or attacker replaying token rather than by credentials user has supplied.
+type InvalidIDTokenError struct {
+	Err error
+}
+
+func (err *InvalidIDTokenError) Error() string {
+	if err == nil {
+		return "<nil>"
+	}
+	if err.Err == nil {
+		return "rocho/oidc: Invalid ID token"
+	}
+	return fmt.Sprintf("rocho/oidc: Invalid ID token: %s", err.Err.Error())
+}
+func (err *InvalidIDTokenError) Unwrap() error {
+	if err == nil {
+		return nil
+	}
+	return err.Err
+}
+
+// Is makes errors.Is(err, rocho.ErrInvalidIDToken) work.
+func (err *InvalidIDTokenError) Is(target error) bool {
+	return target == rocho.ErrInvalidIDToken
+}
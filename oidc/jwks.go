@@ -0,0 +1,262 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/teawithsand/rocho/jwt"
+)
+
+const (
+	defaultKeySetTTL             = time.Hour
+	defaultKeySetRefreshInterval = time.Minute
+)
+
+// jsonWebKey is single key of JWKS as described in RFC 7517.
+// Only fields required for supported algorithms are present.
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type cachedKey struct {
+	id     string
+	method jwt.Method
+}
+
+// KeySet is cached JWKS of provider.
+//
+// Keys are fetched again once TTL passes or when token is signed with unknown key, which happens when provider rotates keys.
+// Fetches are rate-limited with RefreshInterval. If fetch fails, previously fetched keys are still used.
+//
+// Supported keys are RSA(RS256), EC P-256(ES256) and Ed25519(EdDSA).
+// Other keys and keys not intended for signing are ignored.
+type KeySet struct {
+	URL string
+
+	// TTL is time for which keys are cached. If zero, one hour is used.
+	TTL time.Duration
+	// RefreshInterval is minimal time between fetches. If zero, one minute is used.
+	RefreshInterval time.Duration
+
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	lock        sync.Mutex
+	keys        []cachedKey // nil until first successful fetch
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	fetchErr    error
+	// fetching is closed once fetch in progress is done. Nil if there is no fetch in progress.
+	fetching chan struct{}
+}
+
+func (ks *KeySet) now() time.Time {
+	if ks.Now != nil {
+		return ks.Now()
+	}
+	return time.Now()
+}
+
+// Method returns method, which verifies tokens with given header.
+func (ks *KeySet) Method(ctx context.Context, h jwt.Header) (m jwt.Method, err error) {
+	now := ks.now()
+	ttl := ks.TTL
+	if ttl <= 0 {
+		ttl = defaultKeySetTTL
+	}
+
+	ks.lock.Lock()
+	keys, fetchedAt := ks.keys, ks.fetchedAt
+	ks.lock.Unlock()
+
+	if keys == nil || now.Sub(fetchedAt) >= ttl {
+		var ferr error
+		keys, ferr = ks.refresh(ctx, now)
+		if keys == nil {
+			err = ferr
+			return
+		}
+	}
+
+	m = find(keys, h)
+	if m == nil {
+		keys, _ = ks.refresh(ctx, now)
+		m = find(keys, h)
+	}
+	if m == nil {
+		err = jwt.ErrUnknownKeyID
+		return
+	}
+	return
+}
+
+// refresh fetches keys unless last attempt was less than RefreshInterval ago.
+// If other fetch is in progress, it waits for it instead.
+// It returns keys cached after that, which are stale ones if fetch failed, and error of last fetch.
+func (ks *KeySet) refresh(ctx context.Context, now time.Time) (keys []cachedKey, err error) {
+	refreshInterval := ks.RefreshInterval
+	if refreshInterval <= 0 {
+		refreshInterval = defaultKeySetRefreshInterval
+	}
+
+	ks.lock.Lock()
+	if fetching := ks.fetching; fetching != nil {
+		ks.lock.Unlock()
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+		ks.lock.Lock()
+	} else if ks.attemptedAt.IsZero() || now.Sub(ks.attemptedAt) >= refreshInterval {
+		fetching := make(chan struct{})
+		ks.fetching = fetching
+		ks.attemptedAt = now
+		ks.lock.Unlock()
+
+		// never hold lock during fetch, so verification with cached keys is not blocked by slow provider
+		fetched, ferr := ks.fetch(ctx)
+
+		ks.lock.Lock()
+		if ferr == nil {
+			ks.keys = fetched
+			ks.fetchedAt = now
+		}
+		ks.fetchErr = ferr
+		ks.fetching = nil
+		close(fetching)
+	}
+	keys, err = ks.keys, ks.fetchErr
+	ks.lock.Unlock()
+	return
+}
+
+// find returns method for key with ID from header.
+// If header has no key ID, only key set containing single key of header's algorithm is accepted.
+func find(keys []cachedKey, h jwt.Header) (m jwt.Method) {
+	for _, k := range keys {
+		if k.method.Algorithm() != h.Algorithm {
+			continue
+		}
+		if h.KeyID != "" {
+			if k.id == h.KeyID {
+				m = k.method
+				return
+			}
+			continue
+		}
+		if m != nil {
+			m = nil
+			return
+		}
+		m = k.method
+	}
+	return
+}
+
+func (ks *KeySet) fetch(ctx context.Context) (keys []cachedKey, err error) {
+	var set jsonWebKeySet
+	err = fetchJSON(ctx, ks.URL, &set)
+	if err != nil {
+		return
+	}
+
+	keys = make([]cachedKey, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		m := parseKey(&k)
+		if m == nil {
+			continue
+		}
+		if k.Algorithm != "" && k.Algorithm != m.Algorithm() {
+			continue
+		}
+		keys = append(keys, cachedKey{
+			id:     k.KeyID,
+			method: m,
+		})
+	}
+	return
+}
+
+// parseKey returns nil if key is not supported or malformed.
+func parseKey(k *jsonWebKey) (m jwt.Method) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch k.KeyType {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil || len(n) == 0 {
+			return
+		}
+		e, err := decode(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return
+		}
+		m = &jwt.RSA{
+			PublicKey: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			},
+		}
+	case "EC":
+		if k.Curve != "P-256" {
+			return
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return
+		}
+		m = &jwt.ECDSA{
+			PublicKey: pub,
+		}
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return
+		}
+		x, err := decode(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return
+		}
+		m = &jwt.Ed25519{
+			PublicKey: ed25519.PublicKey(x),
+		}
+	}
+	return
+}
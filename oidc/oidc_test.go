@@ -0,0 +1,372 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/teawithsand/rocho"
+	"github.com/teawithsand/rocho/jwt"
+	"golang.org/x/oauth2"
+)
+
+const testClientID = "client"
+
+// testIssuer serves discovery document and JWKS with keys, which can be rotated.
+type testIssuer struct {
+	*httptest.Server
+
+	lock    sync.Mutex
+	keys    map[string]ed25519.PrivateKey
+	failing bool
+	fetches int
+}
+
+func newTestIssuer() *testIssuer {
+	ti := &testIssuer{keys: map[string]ed25519.PrivateKey{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&Metadata{
+			Issuer:                ti.URL,
+			AuthorizationEndpoint: ti.URL + "/auth",
+			TokenEndpoint:         ti.URL + "/token",
+			JWKSURI:               ti.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		ti.lock.Lock()
+		defer ti.lock.Unlock()
+
+		ti.fetches++
+		if ti.failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var set jsonWebKeySet
+		for id, key := range ti.keys {
+			set.Keys = append(set.Keys, jsonWebKey{
+				KeyType: "OKP",
+				KeyID:   id,
+				Use:     "sig",
+				Curve:   "Ed25519",
+				X:       base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(&set)
+	})
+	ti.Server = httptest.NewServer(mux)
+	return ti
+}
+
+// rotate replaces all keys of issuer with new one and returns it.
+func (ti *testIssuer) rotate(t *testing.T, id string) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ti.lock.Lock()
+	defer ti.lock.Unlock()
+	ti.keys = map[string]ed25519.PrivateKey{id: key}
+	return key
+}
+
+func (ti *testIssuer) setFailing(failing bool) {
+	ti.lock.Lock()
+	defer ti.lock.Unlock()
+	ti.failing = failing
+}
+
+func (ti *testIssuer) fetchCount() int {
+	ti.lock.Lock()
+	defer ti.lock.Unlock()
+	return ti.fetches
+}
+
+func signIDToken(t *testing.T, key ed25519.PrivateKey, keyID string, claims interface{}) string {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Encode(&jwt.Ed25519{PrivateKey: key}, keyID, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(token)
+}
+
+func newTestVerifier(t *testing.T, ti *testIssuer, now *time.Time) *Verifier {
+	md, err := Discover(context.Background(), ti.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := func() time.Time {
+		return *now
+	}
+	return &Verifier{
+		Issuer:   md.Issuer,
+		ClientID: testClientID,
+		KeySet:   &KeySet{URL: md.JWKSURI, Now: clock},
+		Now:      clock,
+	}
+}
+
+func validClaims(issuer string, now time.Time) *Claims {
+	c := &Claims{Nonce: "nonce"}
+	c.Iss = issuer
+	c.Sub = "user"
+	c.Aud = jwt.Audience{testClientID}
+	c.Iat = now.Unix()
+	c.Exp = now.Add(time.Hour).Unix()
+	return c
+}
+
+func TestDiscover(t *testing.T) {
+	ti := newTestIssuer()
+	defer ti.Close()
+
+	md, err := Discover(context.Background(), ti.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.JWKSURI != ti.URL+"/jwks" || md.Endpoint().TokenURL != ti.URL+"/token" {
+		t.Errorf("unexpected metadata %+v", md)
+	}
+
+	_, err = Discover(context.Background(), ti.URL+"/")
+	if !errors.Is(err, ErrIssuerMismatch) {
+		t.Errorf("expected ErrIssuerMismatch, got %v", err)
+	}
+}
+
+func TestVerifier_KeyRotation(t *testing.T) {
+	ti := newTestIssuer()
+	defer ti.Close()
+	ctx := context.Background()
+	now := time.Now()
+	v := newTestVerifier(t, ti, &now)
+
+	k1 := ti.rotate(t, "k1")
+	_, err := v.Verify(ctx, signIDToken(t, k1, "k1", validClaims(ti.URL, now)), "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	k2 := ti.rotate(t, "k2")
+	_, err = v.Verify(ctx, signIDToken(t, k2, "k2", validClaims(ti.URL, now)), "nonce")
+	if err != nil {
+		t.Fatalf("expected unknown key to trigger refetch, got %v", err)
+	}
+	if ti.fetchCount() != 2 {
+		t.Errorf("expected 2 fetches, got %d", ti.fetchCount())
+	}
+
+	// refetches caused by unknown keys are rate-limited
+	_, err = v.Verify(ctx, signIDToken(t, k2, "k3", validClaims(ti.URL, now)), "nonce")
+	if !errors.Is(err, jwt.ErrUnknownKeyID) {
+		t.Errorf("expected ErrUnknownKeyID, got %v", err)
+	}
+	if ti.fetchCount() != 2 {
+		t.Errorf("expected no refetch within refresh interval, got %d fetches", ti.fetchCount())
+	}
+}
+
+func TestKeySet_KeepsStaleKeysOnFetchError(t *testing.T) {
+	ti := newTestIssuer()
+	defer ti.Close()
+	ctx := context.Background()
+	now := time.Now()
+	v := newTestVerifier(t, ti, &now)
+
+	ti.setFailing(true)
+	_, err := v.Verify(ctx, signIDToken(t, ti.rotate(t, "k1"), "k1", validClaims(ti.URL, now)), "nonce")
+	var ferr *FetchError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("expected FetchError without cached keys, got %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	ti.setFailing(false)
+	k1 := ti.rotate(t, "k1")
+	_, err = v.Verify(ctx, signIDToken(t, k1, "k1", validClaims(ti.URL, now)), "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	ti.setFailing(true)
+	_, err = v.Verify(ctx, signIDToken(t, k1, "k1", validClaims(ti.URL, now)), "nonce")
+	if err != nil {
+		t.Fatalf("expected stale keys to be used, got %v", err)
+	}
+	if ti.fetchCount() != 3 {
+		t.Errorf("expected expired keys to be refetched, got %d fetches", ti.fetchCount())
+	}
+}
+
+func TestVerifier_InvalidClaims(t *testing.T) {
+	ti := newTestIssuer()
+	defer ti.Close()
+	ctx := context.Background()
+	now := time.Now()
+	v := newTestVerifier(t, ti, &now)
+	key := ti.rotate(t, "k1")
+
+	for name, tc := range map[string]struct {
+		modify func(c *Claims)
+		nonce  string
+		err    error
+	}{
+		"issuer":           {modify: func(c *Claims) { c.Iss = "https://evil.example.com" }, err: jwt.ErrInvalidIssuer},
+		"audience":         {modify: func(c *Claims) { c.Aud = jwt.Audience{"other"} }, err: jwt.ErrInvalidAudience},
+		"no azp":           {modify: func(c *Claims) { c.Aud = jwt.Audience{testClientID, "other"} }, err: ErrInvalidAuthorizedParty},
+		"azp":              {modify: func(c *Claims) { c.AuthorizedParty = "other" }, err: ErrInvalidAuthorizedParty},
+		"expired":          {modify: func(c *Claims) { c.Exp = now.Add(-time.Minute).Unix() }, err: jwt.ErrTokenExpired},
+		"issued in future": {modify: func(c *Claims) { c.Iat = now.Add(time.Minute).Unix() }, err: jwt.ErrTokenIssuedInFuture},
+		"no subject":       {modify: func(c *Claims) { c.Sub = "" }, err: ErrMissingClaim},
+		"nonce":            {nonce: "other", err: ErrInvalidNonce},
+		"valid with azp": {modify: func(c *Claims) {
+			c.Aud = jwt.Audience{testClientID, "other"}
+			c.AuthorizedParty = testClientID
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := validClaims(ti.URL, now)
+			if tc.modify != nil {
+				tc.modify(c)
+			}
+			nonce := "nonce"
+			if tc.nonce != "" {
+				nonce = tc.nonce
+			}
+
+			_, err := v.Verify(ctx, signIDToken(t, key, "k1", c), nonce)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestProvider_NoIDToken(t *testing.T) {
+	p := &Provider{Verifier: &Verifier{}}
+	_, err := p.GetUserData(context.Background(), &rocho.OAuth2AuthData{ExchangedToken: &oauth2.Token{AccessToken: "token"}})
+	if !errors.Is(err, rocho.ErrNoIDToken) {
+		t.Errorf("expected rocho.ErrNoIDToken, got %v", err)
+	}
+}
+
+func TestProvider_InvalidNonce(t *testing.T) {
+	ti := newTestIssuer()
+	defer ti.Close()
+	now := time.Now()
+	p := &Provider{Verifier: newTestVerifier(t, ti, &now)}
+	token := (&oauth2.Token{AccessToken: "token"}).WithExtra(map[string]interface{}{
+		"id_token": signIDToken(t, ti.rotate(t, "k1"), "k1", validClaims(ti.URL, now)),
+	})
+
+	_, err := p.GetUserData(context.Background(), rocho.OAuth2AuthData{ExchangedToken: token, Nonce: "nonce"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = p.GetUserData(context.Background(), rocho.OAuth2AuthData{ExchangedToken: token, Nonce: "other"})
+	var iderr *InvalidIDTokenError
+	if !errors.As(err, &iderr) || !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("expected InvalidIDTokenError with ErrInvalidNonce, got %v", err)
+	}
+}
+
+// Nonce generated by rocho.OAuth2Handler is checked by Verifier against ID token returned from exchange.
+func TestVerifier_OAuth2HandlerNonce(t *testing.T) {
+	ti := newTestIssuer()
+	defer ti.Close()
+	now := time.Now()
+	key := ti.rotate(t, "k1")
+
+	// token endpoint, which issues ID token with nonce taken from its URL, so mismatch can be simulated
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := validClaims(ti.URL, now)
+		c.Nonce = r.URL.Query().Get("nonce")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "token",
+			"token_type":   "Bearer",
+			"id_token":     signIDToken(t, key, "k1", c),
+		})
+	}))
+	defer tokens.Close()
+
+	for name, tc := range map[string]struct {
+		nonce func(authNonce string) string
+		err   error
+	}{
+		"matching":   {nonce: func(authNonce string) string { return authNonce }},
+		"mismatched": {nonce: func(authNonce string) string { return "other" }, err: ErrInvalidNonce},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var handlerErr error
+			var ad rocho.AuthData
+			handler := &rocho.OAuth2Handler{
+				StateManager: &rocho.CookieStateManager{Key: []byte("0123456789abcdef0123456789abcdef")},
+				OAuth2Config: &oauth2.Config{
+					ClientID: testClientID,
+					Endpoint: oauth2.Endpoint{AuthURL: ti.URL + "/auth", AuthStyle: oauth2.AuthStyleInParams},
+				},
+				IDTokenVerifier: newTestVerifier(t, ti, &now),
+				ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+					handlerErr = err
+				},
+				AuthDataReceiver: func(w http.ResponseWriter, r *http.Request, received rocho.AuthData) {
+					ad = received
+				},
+			}
+
+			w := httptest.NewRecorder()
+			handler.InitializeHandler().ServeHTTP(w, httptest.NewRequest("GET", "/login", nil))
+			authURL, err := url.Parse(w.Header().Get("Location"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			q := authURL.Query()
+			handler.OAuth2Config.Endpoint.TokenURL = tokens.URL + "?" + url.Values{"nonce": {tc.nonce(q.Get("nonce"))}}.Encode()
+
+			r := httptest.NewRequest("GET", "/callback?"+url.Values{"state": {q.Get("state")}, "code": {"code"}}.Encode(), nil)
+			for _, c := range w.Result().Cookies() {
+				r.AddCookie(c)
+			}
+			handler.CallbackHandler().ServeHTTP(httptest.NewRecorder(), r)
+
+			if !errors.Is(handlerErr, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, handlerErr)
+			}
+			if (tc.err == nil) != (ad != nil) {
+				t.Errorf("expected AuthDataReceiver to be called only on success, got %v", ad)
+			}
+		})
+	}
+}
+
+func TestInvalidIDTokenError(t *testing.T) {
+	err := error(&InvalidIDTokenError{Err: ErrInvalidNonce})
+	if !errors.Is(err, rocho.ErrInvalidIDToken) || !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("expected error to match rocho.ErrInvalidIDToken and it's cause")
+	}
+	if errors.Is(err, rocho.ErrInvalidCredentials) {
+		t.Errorf("expected error not to match rocho.ErrInvalidCredentials")
+	}
+
+	p := (&rocho.DefaultErrorRenderer{}).Problem(err)
+	if p.Status != http.StatusUnauthorized || p.Code != "invalid_id_token" {
+		t.Errorf("expected invalid_id_token problem, got %+v", p)
+	}
+}
@@ -0,0 +1,59 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/teawithsand/rocho"
+)
+
+// Provider is rocho.UserDataProvider, which returns claims of ID token from rocho.OAuth2AuthData.
+// ID token is taken from "id_token" field of token response, so "openid" scope must be requested.
+// If OAuth2AuthData contains nonce, it's checked against ID token.
+type Provider struct {
+	// Name is returned by UserInfo.ProviderName.
+	Name string
+	// OAuth2ServiceName, if set, makes provider accept only OAuth2AuthData with this service name.
+	OAuth2ServiceName string
+
+	Verifier *Verifier
+}
+
+func (p *Provider) GetUserData(ctx context.Context, ad rocho.AuthData) (ud rocho.UserData, err error) {
+	var oad *rocho.OAuth2AuthData
+	switch v := ad.(type) {
+	case rocho.OAuth2AuthData:
+		oad = &v
+	case *rocho.OAuth2AuthData:
+		oad = v
+	}
+	if oad == nil || (p.OAuth2ServiceName != "" && oad.OAuth2ServiceName != p.OAuth2ServiceName) {
+		err = rocho.ErrAuthDataNotSupported
+		return
+	}
+
+	var rawIDToken string
+	if oad.ExchangedToken != nil {
+		rawIDToken, _ = oad.ExchangedToken.Extra("id_token").(string)
+	}
+	if rawIDToken == "" {
+		err = rocho.ErrNoIDToken
+		return
+	}
+
+	claims, err := p.Verifier.Verify(ctx, rawIDToken, oad.Nonce)
+	var ferr *FetchError
+	if errors.As(err, &ferr) {
+		err = &rocho.ProviderFiledError{Err: err}
+		return
+	} else if err != nil {
+		err = &InvalidIDTokenError{Err: err}
+		return
+	}
+
+	ud = &UserInfo{
+		Provider: p.Name,
+		Claims:   *claims,
+	}
+	return
+}
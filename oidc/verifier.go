@@ -0,0 +1,91 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"time"
+
+	"github.com/teawithsand/rocho/jwt"
+)
+
+// Verifier verifies ID tokens issued for single client.
+type Verifier struct {
+	Issuer   string
+	ClientID string
+	KeySet   *KeySet
+
+	// Leeway is clock skew tolerated when checking time-based claims.
+	Leeway time.Duration
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+// Verify verifies signature and claims of ID token and returns it's claims.
+//
+// Token must be issued by Issuer for ClientID and not be expired.
+// If token has many audiences or "azp" claim, "azp" must be ClientID.
+// If nonce is not empty, "nonce" claim must be equal to it.
+func (v *Verifier) Verify(ctx context.Context, rawIDToken string, nonce string) (c *Claims, err error) {
+	_, payload, err := jwt.Parse([]byte(rawIDToken), func(h jwt.Header) (jwt.Method, error) {
+		return v.KeySet.Method(ctx, h)
+	})
+	if err != nil {
+		return
+	}
+
+	claims := &Claims{}
+	err = json.Unmarshal(payload, claims)
+	if err != nil {
+		err = jwt.ErrMalformedToken
+		return
+	}
+
+	if claims.Iss != v.Issuer {
+		err = jwt.ErrInvalidIssuer
+		return
+	}
+	if !claims.Aud.Contains(v.ClientID) {
+		err = jwt.ErrInvalidAudience
+		return
+	}
+	if (len(claims.Aud) > 1 || claims.AuthorizedParty != "") && claims.AuthorizedParty != v.ClientID {
+		err = ErrInvalidAuthorizedParty
+		return
+	}
+
+	if claims.Sub == "" || claims.Exp == 0 {
+		err = ErrMissingClaim
+		return
+	}
+	now := v.now()
+	if now.Add(-v.Leeway).After(time.Unix(claims.Exp, 0)) {
+		err = jwt.ErrTokenExpired
+		return
+	}
+	if claims.Iat != 0 && now.Add(v.Leeway).Before(time.Unix(claims.Iat, 0)) {
+		err = jwt.ErrTokenIssuedInFuture
+		return
+	}
+
+	if nonce != "" && subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		err = ErrInvalidNonce
+		return
+	}
+
+	c = claims
+	return
+}
+
+// VerifyIDToken implements rocho.IDTokenVerifier.
+func (v *Verifier) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (err error) {
+	_, err = v.Verify(ctx, rawIDToken, nonce)
+	return
+}
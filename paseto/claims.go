@@ -0,0 +1,223 @@
+package paseto
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+// StandardClaims contains registered claims from PASETO spec.
+// It's intended to be embedded in application's AuthToken type.
+//
+// Unlike JWT, times are encoded as RFC 3339 strings. Nil means that claim is not set.
+type StandardClaims struct {
+	Jti string     `json:"jti,omitempty"`
+	Iss string     `json:"iss,omitempty"`
+	Sub string     `json:"sub,omitempty"`
+	Aud string     `json:"aud,omitempty"`
+	Exp *time.Time `json:"exp,omitempty"`
+	Nbf *time.Time `json:"nbf,omitempty"`
+	Iat *time.Time `json:"iat,omitempty"`
+}
+
+// TokenID returns "jti" claim.
+func (c *StandardClaims) TokenID() string {
+	return c.Jti
+}
+
+// Issuer returns "iss" claim.
+func (c *StandardClaims) Issuer() string {
+	return c.Iss
+}
+
+// Subject returns "sub" claim.
+func (c *StandardClaims) Subject() string {
+	return c.Sub
+}
+
+// UserID returns "sub" claim.
+func (c *StandardClaims) UserID() string {
+	return c.Sub
+}
+
+// Audience returns "aud" claim.
+func (c *StandardClaims) Audience() []string {
+	if c.Aud == "" {
+		return nil
+	}
+	return []string{c.Aud}
+}
+
+// ExpiresAt returns "exp" claim. Returns zero time if it's not set.
+func (c *StandardClaims) ExpiresAt() time.Time {
+	return derefTime(c.Exp)
+}
+
+// NotBefore returns "nbf" claim. Returns zero time if it's not set.
+func (c *StandardClaims) NotBefore() time.Time {
+	return derefTime(c.Nbf)
+}
+
+// IssuedAt returns "iat" claim. Returns zero time if it's not set.
+func (c *StandardClaims) IssuedAt() time.Time {
+	return derefTime(c.Iat)
+}
+
+func derefTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// Options contains claim handling options shared by LocalSerializer and PublicSerializer.
+type Options struct {
+	// NewAuthToken creates value, which payload is unmarshaled to.
+	// It should return pointer. If nil, payload is unmarshaled to map[string]interface{}.
+	NewAuthToken func() rocho.AuthToken
+
+	// Issuer is set as "iss" claim if AuthToken does not set it and is required during deserialization if not empty.
+	Issuer string
+	// Audience is set as "aud" claim if AuthToken does not set it and is required during deserialization if not empty.
+	//
+	// It's also used as implicit assertion, so token issued for one audience can't be used by other one,
+	// even if it's claims are not checked.
+	Audience string
+	// TTL is used to set "exp" claim if AuthToken does not set it. Zero means no expiration.
+	TTL time.Duration
+
+	// Leeway is clock skew tolerated when checking time-based claims.
+	Leeway time.Duration
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+func (opts *Options) now() time.Time {
+	if opts.Now != nil {
+		return opts.Now()
+	}
+	return time.Now()
+}
+
+func (opts *Options) implicit() []byte {
+	return []byte(opts.Audience)
+}
+
+func (opts *Options) encodeClaims(at rocho.AuthToken) (payload []byte, err error) {
+	hsi, ok := at.(rocho.HasSecretInfo)
+	if ok {
+		hsi.StripSecretInfo()
+	}
+
+	rawClaims, err := json.Marshal(at)
+	if err != nil {
+		return
+	}
+
+	claims := map[string]json.RawMessage{}
+	err = json.Unmarshal(rawClaims, &claims)
+	if err != nil || claims == nil {
+		err = ErrNotObject
+		return
+	}
+
+	now := opts.now().UTC().Truncate(time.Second)
+	setDefault := func(name string, v interface{}) {
+		if _, ok := claims[name]; ok {
+			return
+		}
+		raw, merr := json.Marshal(v)
+		if merr == nil {
+			claims[name] = raw
+		}
+	}
+
+	setDefault("iat", now)
+	if opts.Issuer != "" {
+		setDefault("iss", opts.Issuer)
+	}
+	if opts.Audience != "" {
+		setDefault("aud", opts.Audience)
+	}
+	if opts.TTL > 0 {
+		setDefault("exp", now.Add(opts.TTL))
+	}
+
+	payload, err = json.Marshal(claims)
+	return
+}
+
+func (opts *Options) decodeClaims(ctx context.Context, payload []byte) (at rocho.AuthToken, err error) {
+	var sc StandardClaims
+	err = json.Unmarshal(payload, &sc)
+	if err != nil {
+		err = ErrMalformedToken
+		return
+	}
+
+	now := opts.now()
+	if sc.Exp != nil && now.Add(-opts.Leeway).After(*sc.Exp) {
+		err = ErrTokenExpired
+		return
+	}
+	if sc.Nbf != nil && now.Add(opts.Leeway).Before(*sc.Nbf) {
+		err = ErrTokenNotValidYet
+		return
+	}
+	if sc.Iat != nil && now.Add(opts.Leeway).Before(*sc.Iat) {
+		err = ErrTokenIssuedInFuture
+		return
+	}
+	if opts.Issuer != "" && sc.Iss != opts.Issuer {
+		err = ErrInvalidIssuer
+		return
+	}
+	if opts.Audience != "" && sc.Aud != opts.Audience {
+		err = ErrInvalidAudience
+		return
+	}
+
+	if opts.NewAuthToken != nil {
+		at = opts.NewAuthToken()
+	} else {
+		at = &map[string]interface{}{}
+	}
+
+	err = json.Unmarshal(payload, at)
+	if err != nil {
+		at = nil
+		return
+	}
+	return
+}
+
+// footer is JSON-encoded footer of tokens created by serializers.
+type footer struct {
+	KeyID string `json:"kid,omitempty"`
+}
+
+func encodeFooter(keyID string) (data []byte, err error) {
+	if keyID == "" {
+		return
+	}
+	data, err = json.Marshal(footer{KeyID: keyID})
+	return
+}
+
+func decodeKeyID(token []byte) (keyID string, err error) {
+	rawFooter, err := Footer(token)
+	if err != nil || len(rawFooter) == 0 {
+		return
+	}
+
+	var f footer
+	err = json.Unmarshal(rawFooter, &f)
+	if err != nil {
+		err = ErrMalformedToken
+		return
+	}
+	keyID = f.KeyID
+	return
+}
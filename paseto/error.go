@@ -0,0 +1,36 @@
+package paseto
+
+import "errors"
+
+// ErrMalformedToken is returned when token has invalid format.
+var ErrMalformedToken = errors.New("rocho/paseto: Malformed token")
+
+// ErrUnsupportedVersion is returned when token has version or purpose other than expected.
+var ErrUnsupportedVersion = errors.New("rocho/paseto: Unsupported token version or purpose")
+
+// ErrInvalidToken is returned when token can't be authenticated.
+var ErrInvalidToken = errors.New("rocho/paseto: Invalid token")
+
+// ErrInvalidKey is returned when key has invalid size.
+var ErrInvalidKey = errors.New("rocho/paseto: Invalid key")
+
+// ErrUnknownKeyID is returned when token's footer points to key, which is not known.
+var ErrUnknownKeyID = errors.New("rocho/paseto: Unknown key ID")
+
+// ErrTokenExpired is returned when "exp" claim is in the past.
+var ErrTokenExpired = errors.New("rocho/paseto: Token has expired")
+
+// ErrTokenNotValidYet is returned when "nbf" claim is in the future.
+var ErrTokenNotValidYet = errors.New("rocho/paseto: Token is not valid yet")
+
+// ErrTokenIssuedInFuture is returned when "iat" claim is in the future.
+var ErrTokenIssuedInFuture = errors.New("rocho/paseto: Token was issued in the future")
+
+// ErrInvalidIssuer is returned when "iss" claim does not match expected issuer.
+var ErrInvalidIssuer = errors.New("rocho/paseto: Invalid issuer")
+
+// ErrInvalidAudience is returned when "aud" claim does not match expected audience.
+var ErrInvalidAudience = errors.New("rocho/paseto: Invalid audience")
+
+// ErrNotObject is returned when AuthToken is not serialized to JSON object, so claims can't be set on it.
+var ErrNotObject = errors.New("rocho/paseto: AuthToken must be serialized to JSON object")
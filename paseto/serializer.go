@@ -0,0 +1,136 @@
+// Package paseto implements rocho.AuthTokenSerializer with PASETO v4.local and v4.public tokens.
+package paseto
+
+import (
+	"context"
+	"crypto/ed25519"
+
+	"github.com/teawithsand/rocho"
+)
+
+// LocalSerializer implements rocho.AuthTokenSerializer using v4.local tokens, which are encrypted and authenticated
+// with symmetric key.
+//
+// If KeyID is set, it's stored in footer as "kid", so keys can be rotated.
+type LocalSerializer struct {
+	Key   []byte // must have KeySize bytes
+	KeyID string
+
+	// Keys contains additional keys used for decryption only, keyed by "kid".
+	Keys map[string][]byte
+
+	Options Options
+}
+
+func (s *LocalSerializer) findKey(keyID string) (key []byte, err error) {
+	if keyID == s.KeyID {
+		key = s.Key
+		return
+	}
+	key, ok := s.Keys[keyID]
+	if !ok {
+		err = ErrUnknownKeyID
+	}
+	return
+}
+
+// SerializeAuthToken encrypts AuthToken with Key.
+func (s *LocalSerializer) SerializeAuthToken(ctx context.Context, at rocho.AuthToken) (data []byte, err error) {
+	payload, err := s.Options.encodeClaims(at)
+	if err != nil {
+		return
+	}
+	f, err := encodeFooter(s.KeyID)
+	if err != nil {
+		return
+	}
+
+	data, err = EncryptV4Local(s.Key, payload, f, s.Options.implicit())
+	return
+}
+
+// DeserializeAuthToken decrypts token, validates its claims and unmarshals its payload to AuthToken.
+func (s *LocalSerializer) DeserializeAuthToken(ctx context.Context, data []byte) (at rocho.AuthToken, err error) {
+	keyID, err := decodeKeyID(data)
+	if err != nil {
+		return
+	}
+	key, err := s.findKey(keyID)
+	if err != nil {
+		return
+	}
+
+	payload, _, err := DecryptV4Local(key, data, s.Options.implicit())
+	if err != nil {
+		return
+	}
+
+	at, err = s.Options.decodeClaims(ctx, payload)
+	return
+}
+
+// PublicSerializer implements rocho.AuthTokenSerializer using v4.public tokens, which are signed with Ed25519.
+// Payload of these tokens is not encrypted.
+//
+// If KeyID is set, it's stored in footer as "kid", so keys can be rotated.
+type PublicSerializer struct {
+	// PrivateKey is required only for serialization. If PublicKey is nil, public part of PrivateKey is used.
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+	KeyID      string
+
+	// Keys contains additional public keys used for verification only, keyed by "kid".
+	Keys map[string]ed25519.PublicKey
+
+	Options Options
+}
+
+func (s *PublicSerializer) findKey(keyID string) (key ed25519.PublicKey, err error) {
+	if keyID == s.KeyID {
+		key = s.PublicKey
+		if key == nil && len(s.PrivateKey) == ed25519.PrivateKeySize {
+			key = s.PrivateKey.Public().(ed25519.PublicKey)
+		}
+		return
+	}
+	key, ok := s.Keys[keyID]
+	if !ok {
+		err = ErrUnknownKeyID
+	}
+	return
+}
+
+// SerializeAuthToken signs AuthToken with PrivateKey.
+func (s *PublicSerializer) SerializeAuthToken(ctx context.Context, at rocho.AuthToken) (data []byte, err error) {
+	payload, err := s.Options.encodeClaims(at)
+	if err != nil {
+		return
+	}
+	f, err := encodeFooter(s.KeyID)
+	if err != nil {
+		return
+	}
+
+	data, err = SignV4Public(s.PrivateKey, payload, f, s.Options.implicit())
+	return
+}
+
+// DeserializeAuthToken verifies token, validates its claims and unmarshals its payload to AuthToken.
+func (s *PublicSerializer) DeserializeAuthToken(ctx context.Context, data []byte) (at rocho.AuthToken, err error) {
+	keyID, err := decodeKeyID(data)
+	if err != nil {
+		return
+	}
+	key, err := s.findKey(keyID)
+	if err != nil {
+		return
+	}
+
+	payload, _, err := VerifyV4Public(key, data, s.Options.implicit())
+	if err != nil {
+		return
+	}
+
+	at, err = s.Options.decodeClaims(ctx, payload)
+	return
+}
@@ -0,0 +1,122 @@
+package paseto
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+type testToken struct {
+	StandardClaims
+	Name string `json:"name"`
+}
+
+func testOptions(now time.Time) Options {
+	return Options{
+		NewAuthToken: func() rocho.AuthToken {
+			return &testToken{}
+		},
+		Issuer:   "issuer",
+		Audience: "app",
+		TTL:      time.Hour,
+		Now: func() time.Time {
+			return now
+		},
+	}
+}
+
+func TestLocalSerializer(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	oldKey := bytes.Repeat([]byte{1}, KeySize)
+	s := &LocalSerializer{
+		Key:     bytes.Repeat([]byte{2}, KeySize),
+		KeyID:   "new",
+		Keys:    map[string][]byte{"old": oldKey},
+		Options: testOptions(now),
+	}
+
+	data, err := s.SerializeAuthToken(ctx, &testToken{Name: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at, err := s.DeserializeAuthToken(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := at.(*testToken)
+	if tok.Name != "user" || tok.Iss != "issuer" || tok.Aud != "app" || !tok.ExpiresAt().Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected token %+v", tok)
+	}
+
+	old := &LocalSerializer{Key: oldKey, KeyID: "old", Options: testOptions(now)}
+	data, err = old.SerializeAuthToken(ctx, &testToken{Name: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.DeserializeAuthToken(ctx, data)
+	if err != nil {
+		t.Errorf("expected token sealed with rotated key to be accepted, got %v", err)
+	}
+
+	old.KeyID = "unknown"
+	data, err = old.SerializeAuthToken(ctx, &testToken{Name: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.DeserializeAuthToken(ctx, data)
+	if !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("expected ErrUnknownKeyID, got %v", err)
+	}
+}
+
+func TestPublicSerializer_Claims(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &PublicSerializer{PrivateKey: priv, Options: testOptions(now)}
+
+	future := now.Add(time.Minute)
+	past := now.Add(-time.Minute)
+	for name, tc := range map[string]struct {
+		token rocho.AuthToken
+		opts  func(opts *Options)
+		err   error
+	}{
+		"valid":            {token: &testToken{}},
+		"expired":          {token: &testToken{StandardClaims: StandardClaims{Exp: &past}}, err: ErrTokenExpired},
+		"not valid yet":    {token: &testToken{StandardClaims: StandardClaims{Nbf: &future}}, err: ErrTokenNotValidYet},
+		"issued in future": {token: &testToken{StandardClaims: StandardClaims{Iat: &future}}, err: ErrTokenIssuedInFuture},
+		"wrong issuer":     {token: &testToken{StandardClaims: StandardClaims{Iss: "other"}}, err: ErrInvalidIssuer},
+		"not object":       {token: []string{"a"}, err: ErrNotObject},
+		// audience is implicit assertion, so mismatch fails before claims are checked
+		"wrong audience": {token: &testToken{}, opts: func(opts *Options) { opts.Audience = "other" }, err: ErrInvalidToken},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := s.SerializeAuthToken(ctx, tc.token)
+			if err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			verifier := &PublicSerializer{PublicKey: priv.Public().(ed25519.PublicKey), Options: testOptions(now)}
+			if tc.opts != nil {
+				tc.opts(&verifier.Options)
+			}
+			_, err = verifier.DeserializeAuthToken(ctx, data)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package paseto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	v4LocalHeader  = "v4.local."
+	v4PublicHeader = "v4.public."
+)
+
+// KeySize is size of symmetric key used by v4.local.
+const KeySize = 32
+
+const (
+	v4NonceSize = 32
+	v4MACSize   = 32
+)
+
+var encoding = base64.RawURLEncoding
+
+// EncryptV4Local creates v4.local token from given message, footer and implicit assertion.
+func EncryptV4Local(key, message, footer, implicit []byte) (token []byte, err error) {
+	if len(key) != KeySize {
+		err = ErrInvalidKey
+		return
+	}
+
+	n := make([]byte, v4NonceSize)
+	_, err = io.ReadFull(rand.Reader, n)
+	if err != nil {
+		return
+	}
+
+	token, err = encryptV4Local(key, n, message, footer, implicit)
+	return
+}
+
+// encryptV4Local creates v4.local token using given nonce, so it's deterministic and may be checked against test vectors.
+func encryptV4Local(key, n, message, footer, implicit []byte) (token []byte, err error) {
+	ek, n2, ak, err := v4LocalKeys(key, n)
+	if err != nil {
+		return
+	}
+
+	c := make([]byte, len(message))
+	cipher, err := chacha20.NewUnauthenticatedCipher(ek, n2)
+	if err != nil {
+		return
+	}
+	cipher.XORKeyStream(c, message)
+
+	t, err := v4LocalMAC(ak, n, c, footer, implicit)
+	if err != nil {
+		return
+	}
+
+	body := make([]byte, 0, len(n)+len(c)+len(t))
+	body = append(body, n...)
+	body = append(body, c...)
+	body = append(body, t...)
+
+	token = assemble(v4LocalHeader, body, footer)
+	return
+}
+
+// DecryptV4Local verifies and decrypts v4.local token, returning its message and footer.
+func DecryptV4Local(key, token, implicit []byte) (message, footer []byte, err error) {
+	if len(key) != KeySize {
+		err = ErrInvalidKey
+		return
+	}
+
+	body, footer, err := split(v4LocalHeader, token)
+	if err != nil {
+		return
+	}
+	if len(body) < v4NonceSize+v4MACSize {
+		err = ErrMalformedToken
+		return
+	}
+
+	n := body[:v4NonceSize]
+	c := body[v4NonceSize : len(body)-v4MACSize]
+	t := body[len(body)-v4MACSize:]
+
+	ek, n2, ak, err := v4LocalKeys(key, n)
+	if err != nil {
+		return
+	}
+
+	expected, err := v4LocalMAC(ak, n, c, footer, implicit)
+	if err != nil {
+		return
+	}
+	if subtle.ConstantTimeCompare(expected, t) != 1 {
+		err = ErrInvalidToken
+		return
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(ek, n2)
+	if err != nil {
+		return
+	}
+	message = make([]byte, len(c))
+	cipher.XORKeyStream(message, c)
+	return
+}
+
+// SignV4Public creates v4.public token from given message, footer and implicit assertion.
+func SignV4Public(key ed25519.PrivateKey, message, footer, implicit []byte) (token []byte, err error) {
+	if len(key) != ed25519.PrivateKeySize {
+		err = ErrInvalidKey
+		return
+	}
+
+	sig := ed25519.Sign(key, pae([]byte(v4PublicHeader), message, footer, implicit))
+
+	body := make([]byte, 0, len(message)+len(sig))
+	body = append(body, message...)
+	body = append(body, sig...)
+
+	token = assemble(v4PublicHeader, body, footer)
+	return
+}
+
+// VerifyV4Public verifies v4.public token, returning its message and footer.
+func VerifyV4Public(key ed25519.PublicKey, token, implicit []byte) (message, footer []byte, err error) {
+	if len(key) != ed25519.PublicKeySize {
+		err = ErrInvalidKey
+		return
+	}
+
+	body, footer, err := split(v4PublicHeader, token)
+	if err != nil {
+		return
+	}
+	if len(body) < ed25519.SignatureSize {
+		err = ErrMalformedToken
+		return
+	}
+
+	m := body[:len(body)-ed25519.SignatureSize]
+	sig := body[len(body)-ed25519.SignatureSize:]
+
+	if !ed25519.Verify(key, pae([]byte(v4PublicHeader), m, footer, implicit), sig) {
+		err = ErrInvalidToken
+		return
+	}
+	message = m
+	return
+}
+
+// Footer returns unverified footer of token.
+// It's useful for getting key ID before verification.
+func Footer(token []byte) (footer []byte, err error) {
+	parts := bytes.Split(token, []byte("."))
+	switch len(parts) {
+	case 3:
+	case 4:
+		footer, err = encoding.DecodeString(string(parts[3]))
+		if err != nil {
+			err = ErrMalformedToken
+		}
+	default:
+		err = ErrMalformedToken
+	}
+	return
+}
+
+func v4LocalKeys(key, n []byte) (ek, n2, ak []byte, err error) {
+	h, err := blake2b.New(56, key)
+	if err != nil {
+		return
+	}
+	_, _ = h.Write([]byte("paseto-encryption-key"))
+	_, _ = h.Write(n)
+	tmp := h.Sum(nil)
+	ek, n2 = tmp[:32], tmp[32:]
+
+	h, err = blake2b.New(32, key)
+	if err != nil {
+		return
+	}
+	_, _ = h.Write([]byte("paseto-auth-key-for-aead"))
+	_, _ = h.Write(n)
+	ak = h.Sum(nil)
+	return
+}
+
+func v4LocalMAC(ak, n, c, footer, implicit []byte) (t []byte, err error) {
+	h, err := blake2b.New(v4MACSize, ak)
+	if err != nil {
+		return
+	}
+	_, _ = h.Write(pae([]byte(v4LocalHeader), n, c, footer, implicit))
+	t = h.Sum(nil)
+	return
+}
+
+// pae implements pre-authentication encoding from PASETO spec.
+func pae(pieces ...[]byte) []byte {
+	size := 8
+	for _, p := range pieces {
+		size += 8 + len(p)
+	}
+
+	res := make([]byte, 8, size)
+	binary.LittleEndian.PutUint64(res, uint64(len(pieces))&^(1<<63))
+	for _, p := range pieces {
+		var l [8]byte
+		binary.LittleEndian.PutUint64(l[:], uint64(len(p))&^(1<<63))
+		res = append(res, l[:]...)
+		res = append(res, p...)
+	}
+	return res
+}
+
+func assemble(header string, body, footer []byte) (token []byte) {
+	token = make([]byte, 0, len(header)+encoding.EncodedLen(len(body))+1+encoding.EncodedLen(len(footer)))
+	token = append(token, header...)
+	token = append(token, encoding.EncodeToString(body)...)
+	if len(footer) > 0 {
+		token = append(token, '.')
+		token = append(token, encoding.EncodeToString(footer)...)
+	}
+	return
+}
+
+func split(header string, token []byte) (body, footer []byte, err error) {
+	if !bytes.HasPrefix(token, []byte(header)) {
+		err = ErrUnsupportedVersion
+		return
+	}
+
+	parts := bytes.Split(token[len(header):], []byte("."))
+	if len(parts) > 2 {
+		err = ErrMalformedToken
+		return
+	}
+
+	body, err = encoding.DecodeString(string(parts[0]))
+	if err != nil {
+		err = ErrMalformedToken
+		return
+	}
+	if len(parts) == 2 {
+		footer, err = encoding.DecodeString(string(parts[1]))
+		if err != nil {
+			err = ErrMalformedToken
+			return
+		}
+	}
+	return
+}
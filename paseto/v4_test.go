@@ -0,0 +1,175 @@
+package paseto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	res, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// PASETO test vector 4-S-1
+func TestV4Public_Vector(t *testing.T) {
+	const token = "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9" +
+		"bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA"
+	const message = `{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`
+	seed := mustHex(t, "b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774")
+	pub := ed25519.PublicKey(mustHex(t, "1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2"))
+
+	m, f, err := VerifyV4Public(pub, []byte(token), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m) != message || len(f) != 0 {
+		t.Errorf("unexpected message %q and footer %q", m, f)
+	}
+
+	signed, err := SignV4Public(ed25519.NewKeyFromSeed(seed), []byte(message), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(signed) != token {
+		t.Errorf("expected %s, got %s", token, signed)
+	}
+}
+
+// PASETO test vectors 4-E-1 and 4-E-2
+func TestV4Local_Vectors(t *testing.T) {
+	key := mustHex(t, "707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f")
+	nonce := make([]byte, v4NonceSize)
+
+	for name, tc := range map[string]struct {
+		message string
+		token   string
+	}{
+		"4-E-1": {
+			message: `{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`,
+			token:   "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg",
+		},
+		"4-E-2": {
+			message: `{"data":"this is a hidden message","exp":"2022-01-01T00:00:00+00:00"}`,
+			token:   "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvS2csCgglvpk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XIemu9chy3WVKvRBfg6t8wwYHK0ArLxxfZP73W_vfwt5A",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m, f, err := DecryptV4Local(key, []byte(tc.token), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(m) != tc.message || len(f) != 0 {
+				t.Errorf("unexpected message %q and footer %q", m, f)
+			}
+
+			token, err := encryptV4Local(key, nonce, []byte(tc.message), nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(token) != tc.token {
+				t.Errorf("expected %s, got %s", tc.token, token)
+			}
+		})
+	}
+}
+
+func TestV4Public_Negative(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := SignV4Public(priv, []byte("message"), []byte("footer"), []byte("implicit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = VerifyV4Public(pub, token, []byte("other"))
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected implicit assertion mismatch to fail, got %v", err)
+	}
+
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = VerifyV4Public(otherPub, token, []byte("implicit"))
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected wrong key to fail, got %v", err)
+	}
+
+	forged := append(bytes.TrimSuffix(token, []byte(encoding.EncodeToString([]byte("footer")))),
+		encoding.EncodeToString([]byte("forged"))...)
+	_, _, err = VerifyV4Public(pub, forged, []byte("implicit"))
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected changed footer to fail, got %v", err)
+	}
+
+	_, _, err = VerifyV4Public(pub, append([]byte("v3"), token[2:]...), []byte("implicit"))
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestV4Local(t *testing.T) {
+	key := mustHex(t, "707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f")
+
+	token, err := EncryptV4Local(key, []byte("secret message"), []byte("footer"), []byte("implicit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(token, []byte(encoding.EncodeToString([]byte("secret")))) {
+		t.Error("expected message to be encrypted")
+	}
+
+	m, f, err := DecryptV4Local(key, token, []byte("implicit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m) != "secret message" || string(f) != "footer" {
+		t.Errorf("unexpected message %q and footer %q", m, f)
+	}
+
+	f, err = Footer(token)
+	if err != nil || string(f) != "footer" {
+		t.Errorf("unexpected footer %q, %v", f, err)
+	}
+
+	_, _, err = DecryptV4Local(key, token, nil)
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected implicit assertion mismatch to fail, got %v", err)
+	}
+
+	body, _, err := split(v4LocalHeader, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body[v4NonceSize] ^= 1
+	tampered := assemble(v4LocalHeader, body, []byte("footer"))
+	_, _, err = DecryptV4Local(key, tampered, []byte("implicit"))
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected tampered ciphertext to fail, got %v", err)
+	}
+
+	otherKey := bytes.Repeat([]byte{1}, KeySize)
+	_, _, err = DecryptV4Local(otherKey, token, []byte("implicit"))
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected wrong key to fail, got %v", err)
+	}
+
+	_, _, err = DecryptV4Local(key[:16], token, []byte("implicit"))
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+
+	_, _, err = DecryptV4Local(key, []byte("v4.public."+string(token[len(v4LocalHeader):])), []byte("implicit"))
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package password
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	argon2idID     = "argon2id"
+	bcryptID       = "bcrypt"
+	scryptID       = "scrypt"
+	pbkdf2SHA256ID = "pbkdf2-sha256"
+)
+
+const (
+	defaultSaltLength = 16
+	defaultKeyLength  = 32
+)
+
+// Argon2id implements Algorithm using argon2id. It's recommended one.
+// Zero values of parameters are replaced with defaults: 64 MiB of memory, 3 iterations and parallelism of 4.
+type Argon2id struct {
+	Memory      uint32 // in KiB
+	Iterations  uint32
+	Parallelism uint8
+}
+
+func (a *Argon2id) params() (memory, iterations uint32, parallelism uint8) {
+	memory, iterations, parallelism = a.Memory, a.Iterations, a.Parallelism
+	if memory == 0 {
+		memory = 64 * 1024
+	}
+	if iterations == 0 {
+		iterations = 3
+	}
+	if parallelism == 0 {
+		parallelism = 4
+	}
+	return
+}
+
+func (*Argon2id) ID() string {
+	return argon2idID
+}
+
+func (a *Argon2id) Hash(password string) (encoded string, err error) {
+	salt, err := randomSalt(defaultSaltLength)
+	if err != nil {
+		return
+	}
+
+	m, t, p := a.params()
+	key := argon2.IDKey([]byte(password), salt, t, m, p, defaultKeyLength)
+	encoded = encodePHC(argon2idID, strconv.Itoa(argon2.Version), fmt.Sprintf("m=%d,t=%d,p=%d", m, t, p), salt, key)
+	return
+}
+
+func (a *Argon2id) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	h, err := parsePHC(encoded)
+	if err != nil {
+		return
+	}
+	if h.ID != argon2idID {
+		err = ErrUnknownAlgorithm
+		return
+	}
+	if h.Version != strconv.Itoa(argon2.Version) {
+		err = ErrUnknownAlgorithm
+		return
+	}
+
+	m, err := h.intParam("m")
+	if err != nil {
+		return
+	}
+	t, err := h.intParam("t")
+	if err != nil {
+		return
+	}
+	p, err := h.intParam("p")
+	if err != nil || p > 255 {
+		err = ErrMalformedHash
+		return
+	}
+
+	key := argon2.IDKey([]byte(password), h.Salt, uint32(t), uint32(m), uint8(p), uint32(len(h.Hash)))
+	ok = equal(key, h.Hash)
+
+	cm, ct, cp := a.params()
+	needsRehash = ok && (uint32(m) != cm || uint32(t) != ct || uint8(p) != cp)
+	return
+}
+
+// Bcrypt implements Algorithm using bcrypt.
+// It's supported mostly for legacy hashes. If Cost is zero, 12 is used.
+type Bcrypt struct {
+	Cost int
+}
+
+func (b *Bcrypt) cost() int {
+	if b.Cost == 0 {
+		return 12
+	}
+	return b.Cost
+}
+
+func (*Bcrypt) ID() string {
+	return bcryptID
+}
+
+func (b *Bcrypt) Hash(password string) (encoded string, err error) {
+	raw, err := bcrypt.GenerateFromPassword([]byte(password), b.cost())
+	if err != nil {
+		return
+	}
+	encoded = string(raw)
+	return
+}
+
+func (b *Bcrypt) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		err = ErrMalformedHash
+		return
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		err = nil
+		return
+	} else if err != nil {
+		err = ErrMalformedHash
+		return
+	}
+
+	ok = true
+	needsRehash = cost != b.cost()
+	return
+}
+
+// Scrypt implements Algorithm using scrypt.
+// Zero values of parameters are replaced with defaults: N=2^17, r=8 and p=1.
+type Scrypt struct {
+	LogN uint8
+	R    int
+	P    int
+}
+
+func (s *Scrypt) params() (logN uint8, r, p int) {
+	logN, r, p = s.LogN, s.R, s.P
+	if logN == 0 {
+		logN = 17
+	}
+	if r == 0 {
+		r = 8
+	}
+	if p == 0 {
+		p = 1
+	}
+	return
+}
+
+func (*Scrypt) ID() string {
+	return scryptID
+}
+
+func (s *Scrypt) Hash(password string) (encoded string, err error) {
+	salt, err := randomSalt(defaultSaltLength)
+	if err != nil {
+		return
+	}
+
+	logN, r, p := s.params()
+	key, err := scrypt.Key([]byte(password), salt, 1<<logN, r, p, defaultKeyLength)
+	if err != nil {
+		return
+	}
+	encoded = encodePHC(scryptID, "", fmt.Sprintf("ln=%d,r=%d,p=%d", logN, r, p), salt, key)
+	return
+}
+
+func (s *Scrypt) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	h, err := parsePHC(encoded)
+	if err != nil {
+		return
+	}
+	if h.ID != scryptID {
+		err = ErrUnknownAlgorithm
+		return
+	}
+
+	logN, err := h.intParam("ln")
+	if err != nil || logN > 30 {
+		err = ErrMalformedHash
+		return
+	}
+	r, err := h.intParam("r")
+	if err != nil {
+		return
+	}
+	p, err := h.intParam("p")
+	if err != nil {
+		return
+	}
+
+	key, err := scrypt.Key([]byte(password), h.Salt, 1<<uint(logN), r, p, len(h.Hash))
+	if err != nil {
+		err = ErrMalformedHash
+		return
+	}
+	ok = equal(key, h.Hash)
+
+	cLogN, cr, cp := s.params()
+	needsRehash = ok && (uint8(logN) != cLogN || r != cr || p != cp)
+	return
+}
+
+// PBKDF2SHA256 implements Algorithm using PBKDF2 with HMAC-SHA256.
+// It should be used only where FIPS compliance is required. If Iterations is zero, 600000 is used.
+type PBKDF2SHA256 struct {
+	Iterations int
+}
+
+func (pb *PBKDF2SHA256) iterations() int {
+	if pb.Iterations == 0 {
+		return 600000
+	}
+	return pb.Iterations
+}
+
+func (*PBKDF2SHA256) ID() string {
+	return pbkdf2SHA256ID
+}
+
+func (pb *PBKDF2SHA256) Hash(password string) (encoded string, err error) {
+	salt, err := randomSalt(defaultSaltLength)
+	if err != nil {
+		return
+	}
+
+	i := pb.iterations()
+	key := pbkdf2.Key([]byte(password), salt, i, defaultKeyLength, sha256.New)
+	encoded = encodePHC(pbkdf2SHA256ID, "", fmt.Sprintf("i=%d", i), salt, key)
+	return
+}
+
+func (pb *PBKDF2SHA256) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	h, err := parsePHC(encoded)
+	if err != nil {
+		return
+	}
+	if h.ID != pbkdf2SHA256ID {
+		err = ErrUnknownAlgorithm
+		return
+	}
+
+	i, err := h.intParam("i")
+	if err != nil {
+		return
+	}
+
+	key := pbkdf2.Key([]byte(password), h.Salt, i, len(h.Hash), sha256.New)
+	ok = equal(key, h.Hash)
+	needsRehash = ok && i != pb.iterations()
+	return
+}
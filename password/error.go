@@ -0,0 +1,9 @@
+package password
+
+import "errors"
+
+// ErrMalformedHash is returned when encoded hash can't be parsed.
+var ErrMalformedHash = errors.New("rocho/password: Malformed hash")
+
+// ErrUnknownAlgorithm is returned when encoded hash was created with unsupported algorithm.
+var ErrUnknownAlgorithm = errors.New("rocho/password: Unknown hash algorithm")
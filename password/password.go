@@ -0,0 +1,194 @@
+// Package password implements password hashing with argon2id, bcrypt, scrypt and PBKDF2-SHA256.
+//
+// Hashes are encoded as PHC strings, except bcrypt, which uses it's own well known format.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Algorithm hashes passwords using single algorithm with specific parameters.
+type Algorithm interface {
+	// ID returns identifier of algorithm in encoded hashes, for instance "argon2id".
+	ID() string
+	Hash(password string) (encoded string, err error)
+	// Verify checks password against hash created by this algorithm, possibly with different parameters.
+	// needsRehash is set when hash was created with parameters other than these of this Algorithm.
+	Verify(password, encoded string) (ok, needsRehash bool, err error)
+}
+
+// Hasher hashes passwords and verifies them.
+type Hasher interface {
+	Hash(password string) (encoded string, err error)
+	// Verify checks password against encoded hash.
+	// needsRehash is set when password matches, but hash should be replaced with new one,
+	// since it was created with outdated algorithm or parameters.
+	Verify(password, encoded string) (ok, needsRehash bool, err error)
+}
+
+// DefaultHasher implements Hasher.
+// It creates hashes using Algorithm and verifies hashes created by any supported algorithm,
+// so hashes can be migrated transparently on login.
+type DefaultHasher struct {
+	// Algorithm is used for new hashes. If nil, Argon2id with default parameters is used.
+	Algorithm Algorithm
+}
+
+func (h *DefaultHasher) algorithm() Algorithm {
+	if h.Algorithm != nil {
+		return h.Algorithm
+	}
+	return &Argon2id{}
+}
+
+// Hash hashes password with Algorithm.
+func (h *DefaultHasher) Hash(password string) (encoded string, err error) {
+	encoded, err = h.algorithm().Hash(password)
+	return
+}
+
+// Verify checks password against hash created by any supported algorithm.
+func (h *DefaultHasher) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	current := h.algorithm()
+	id := hashID(encoded)
+	if id == "" {
+		err = ErrMalformedHash
+		return
+	}
+
+	if id == current.ID() {
+		ok, needsRehash, err = current.Verify(password, encoded)
+		return
+	}
+
+	var alg Algorithm
+	switch id {
+	case argon2idID:
+		alg = &Argon2id{}
+	case bcryptID:
+		alg = &Bcrypt{}
+	case scryptID:
+		alg = &Scrypt{}
+	case pbkdf2SHA256ID:
+		alg = &PBKDF2SHA256{}
+	default:
+		err = ErrUnknownAlgorithm
+		return
+	}
+
+	ok, _, err = alg.Verify(password, encoded)
+	// algorithm itself is outdated
+	needsRehash = ok
+	return
+}
+
+// hashID returns ID of algorithm used to create encoded hash.
+func hashID(encoded string) string {
+	if !strings.HasPrefix(encoded, "$") {
+		return ""
+	}
+	end := strings.IndexByte(encoded[1:], '$')
+	if end < 0 {
+		return ""
+	}
+	id := encoded[1 : end+1]
+	switch id {
+	case "2a", "2b", "2y":
+		return bcryptID
+	}
+	return id
+}
+
+// PHC strings use standard base64 without padding.
+var phcEncoding = base64.RawStdEncoding
+
+func randomSalt(size int) (salt []byte, err error) {
+	salt = make([]byte, size)
+	_, err = io.ReadFull(rand.Reader, salt)
+	return
+}
+
+// phcHash is parsed PHC string of form $id[$v=version]$param=value,...$salt$hash.
+type phcHash struct {
+	ID      string
+	Version string
+	Params  map[string]string
+	Salt    []byte
+	Hash    []byte
+}
+
+func parsePHC(encoded string) (h phcHash, err error) {
+	parts := strings.Split(encoded, "$")
+	// leading "$" gives empty first part
+	if len(parts) < 5 || len(parts) > 6 || parts[0] != "" {
+		err = ErrMalformedHash
+		return
+	}
+	h.ID = parts[1]
+	parts = parts[2:]
+
+	if len(parts) == 4 {
+		if !strings.HasPrefix(parts[0], "v=") {
+			err = ErrMalformedHash
+			return
+		}
+		h.Version = parts[0][2:]
+		parts = parts[1:]
+	}
+
+	h.Params = map[string]string{}
+	for _, kv := range strings.Split(parts[0], ",") {
+		i := strings.IndexByte(kv, '=')
+		if i <= 0 {
+			err = ErrMalformedHash
+			return
+		}
+		h.Params[kv[:i]] = kv[i+1:]
+	}
+
+	h.Salt, err = phcEncoding.DecodeString(parts[1])
+	if err != nil {
+		err = ErrMalformedHash
+		return
+	}
+	h.Hash, err = phcEncoding.DecodeString(parts[2])
+	if err != nil || len(h.Hash) == 0 {
+		err = ErrMalformedHash
+		return
+	}
+	return
+}
+
+func (h *phcHash) intParam(name string) (v int, err error) {
+	v, err = strconv.Atoi(h.Params[name])
+	if err != nil || v <= 0 {
+		err = ErrMalformedHash
+	}
+	return
+}
+
+func encodePHC(id, version, params string, salt, hash []byte) string {
+	var b strings.Builder
+	b.WriteString("$")
+	b.WriteString(id)
+	if version != "" {
+		b.WriteString("$v=")
+		b.WriteString(version)
+	}
+	b.WriteString("$")
+	b.WriteString(params)
+	b.WriteString("$")
+	b.WriteString(phcEncoding.EncodeToString(salt))
+	b.WriteString("$")
+	b.WriteString(phcEncoding.EncodeToString(hash))
+	return b.String()
+}
+
+func equal(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
@@ -0,0 +1,178 @@
+package password
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	res, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestAlgorithms_KnownVectors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		alg      Algorithm
+		password string
+		encoded  string
+	}{
+		// RFC 7914 section 12
+		"scrypt": {
+			alg:      &Scrypt{LogN: 10, R: 8, P: 16},
+			password: "password",
+			encoded: encodePHC(scryptID, "", "ln=10,r=8,p=16", []byte("NaCl"), mustHex(t,
+				"fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640")),
+		},
+		// RFC 7914 section 11
+		"pbkdf2-sha256": {
+			alg:      &PBKDF2SHA256{Iterations: 1},
+			password: "passwd",
+			encoded: encodePHC(pbkdf2SHA256ID, "", "i=1", []byte("salt"), mustHex(t,
+				"55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783")),
+		},
+		// OpenBSD bcrypt test vector
+		"bcrypt": {
+			alg:      &Bcrypt{Cost: 5},
+			password: "U*U",
+			encoded:  "$2a$05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ok, needsRehash, err := tc.alg.Verify(tc.password, tc.encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok || needsRehash {
+				t.Errorf("expected match without rehash, got ok=%v needsRehash=%v", ok, needsRehash)
+			}
+
+			ok, _, err = tc.alg.Verify(tc.password+"x", tc.encoded)
+			if err != nil || ok {
+				t.Errorf("expected wrong password to be rejected, got ok=%v err=%v", ok, err)
+			}
+		})
+	}
+}
+
+// cheapAlgorithms returns algorithms with parameters low enough for tests.
+func cheapAlgorithms() []Algorithm {
+	return []Algorithm{
+		&Argon2id{Memory: 64, Iterations: 1, Parallelism: 1},
+		&Bcrypt{Cost: 4},
+		&Scrypt{LogN: 4, R: 1, P: 1},
+		&PBKDF2SHA256{Iterations: 10},
+	}
+}
+
+func TestAlgorithms_RoundTrip(t *testing.T) {
+	for _, alg := range cheapAlgorithms() {
+		t.Run(alg.ID(), func(t *testing.T) {
+			h := &DefaultHasher{Algorithm: alg}
+			encoded, err := h.Hash("secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hashID(encoded) != alg.ID() {
+				t.Errorf("unexpected hash %s", encoded)
+			}
+
+			ok, needsRehash, err := h.Verify("secret", encoded)
+			if err != nil || !ok || needsRehash {
+				t.Errorf("expected match, got ok=%v needsRehash=%v err=%v", ok, needsRehash, err)
+			}
+
+			ok, needsRehash, err = h.Verify("Secret", encoded)
+			if err != nil || ok || needsRehash {
+				t.Errorf("expected mismatch, got ok=%v needsRehash=%v err=%v", ok, needsRehash, err)
+			}
+		})
+	}
+}
+
+func TestDefaultHasher_NeedsRehash(t *testing.T) {
+	current := &DefaultHasher{Algorithm: &Argon2id{Memory: 64, Iterations: 1, Parallelism: 1}}
+
+	for _, alg := range cheapAlgorithms()[1:] {
+		t.Run("algorithm "+alg.ID(), func(t *testing.T) {
+			encoded, err := alg.Hash("secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ok, needsRehash, err := current.Verify("secret", encoded)
+			if err != nil || !ok || !needsRehash {
+				t.Errorf("expected outdated algorithm to need rehash, got ok=%v needsRehash=%v err=%v", ok, needsRehash, err)
+			}
+
+			ok, needsRehash, err = current.Verify("wrong", encoded)
+			if err != nil || ok || needsRehash {
+				t.Errorf("expected wrong password not to need rehash, got ok=%v needsRehash=%v err=%v", ok, needsRehash, err)
+			}
+		})
+	}
+
+	for name, tc := range map[string]struct {
+		old, current Algorithm
+	}{
+		"argon2id": {old: &Argon2id{Memory: 64, Iterations: 1, Parallelism: 1}, current: &Argon2id{Memory: 64, Iterations: 2, Parallelism: 1}},
+		"bcrypt":   {old: &Bcrypt{Cost: 4}, current: &Bcrypt{Cost: 5}},
+		"scrypt":   {old: &Scrypt{LogN: 4, R: 1, P: 1}, current: &Scrypt{LogN: 5, R: 1, P: 1}},
+		"pbkdf2":   {old: &PBKDF2SHA256{Iterations: 10}, current: &PBKDF2SHA256{Iterations: 20}},
+	} {
+		t.Run("parameters "+name, func(t *testing.T) {
+			encoded, err := tc.old.Hash("secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ok, needsRehash, err := (&DefaultHasher{Algorithm: tc.current}).Verify("secret", encoded)
+			if err != nil || !ok || !needsRehash {
+				t.Errorf("expected changed parameters to need rehash, got ok=%v needsRehash=%v err=%v", ok, needsRehash, err)
+			}
+		})
+	}
+}
+
+func TestDefaultHasher_MalformedHash(t *testing.T) {
+	h := &DefaultHasher{}
+	for encoded, expected := range map[string]error{
+		"":                                       ErrMalformedHash,
+		"plain":                                  ErrMalformedHash,
+		"$md5$abc":                               ErrUnknownAlgorithm,
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA":     ErrMalformedHash,
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$aGFzaA": ErrMalformedHash,
+		"$argon2id$v=19$m=64,t=x,p=1$c2FsdA$aGFzaA":          ErrMalformedHash,
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdA$aGFzaA":          ErrUnknownAlgorithm,
+		"$argon2id$m=64,t=1,p=1$c2FsdA$aGFzaA":               ErrUnknownAlgorithm,
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$":                ErrMalformedHash,
+		"$scrypt$ln=40,r=8,p=1$c2FsdA$aGFzaA":                ErrMalformedHash,
+		"$pbkdf2-sha256$i=0$c2FsdA$aGFzaA":                   ErrMalformedHash,
+		"$2a$05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb": ErrMalformedHash,
+	} {
+		ok, _, err := h.Verify("secret", encoded)
+		if ok || !errors.Is(err, expected) {
+			t.Errorf("%q: expected %v, got ok=%v err=%v", encoded, expected, ok, err)
+		}
+	}
+}
+
+func TestParsePHC(t *testing.T) {
+	h, err := parsePHC("$argon2id$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$aGFzaA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.ID != "argon2id" || h.Version != "19" || h.Params["m"] != "65536" || h.Params["t"] != "2" || h.Params["p"] != "4" ||
+		string(h.Salt) != "somesalt" || string(h.Hash) != "hash" {
+		t.Errorf("unexpected result %+v", h)
+	}
+
+	encoded := encodePHC(h.ID, h.Version, "m=65536,t=2,p=4", h.Salt, h.Hash)
+	if encoded != "$argon2id$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$aGFzaA" {
+		t.Errorf("unexpected encoding %s", encoded)
+	}
+}
@@ -1,6 +1,10 @@
 package perm
 
-import "context"
+import (
+	"context"
+
+	"github.com/teawithsand/rocho"
+)
 
 // Permission is kind of permission that user can have on subject.
 // It should be declared on library level.
@@ -51,6 +55,21 @@ const (
 	VoterDeny
 )
 
+// String returns name of VoterResult.
+func (vr VoterResult) String() string {
+	switch vr {
+	case VoterNeutral:
+		return "neutral"
+	case VoterNoSupport:
+		return "no_support"
+	case VoterAgree:
+		return "agree"
+	case VoterDeny:
+		return "deny"
+	}
+	return "undefined"
+}
+
 // Check contains single request for checking given permission for given user on given subject.
 type Check struct {
 	Permission Permission
@@ -90,18 +109,43 @@ type NamedVoter struct {
 // It allows permission if no voter votes against, and at least one voter votes for it.
 type DefaultManager struct {
 	Voters []NamedVoter
+
+	// Events receives rocho.EventPermissionGranted and rocho.EventPermissionDenied events with CheckResult as data.
+	// It may be nil.
+	Events *rocho.EventBus
 }
 
 func (dm *DefaultManager) CheckPermission(ctx context.Context, c Check) (res CheckResult, err error) {
+	res.Permission = c.Permission
+	res.User = c.User
+	res.Subject = c.Subject
 	res.VoterResults = map[string]VoterResult{}
+
+	defer func() {
+		if err != nil {
+			return
+		}
+		e := rocho.Event{
+			Type: rocho.EventPermissionDenied,
+			Data: res,
+		}
+		if res.IsAllowed {
+			e.Type = rocho.EventPermissionGranted
+		}
+		if uat, ok := c.User.(rocho.UserAuthToken); ok {
+			e.UserID = uat.UserID()
+		}
+		dm.Events.Emit(ctx, e)
+	}()
+
 	agreed := 0
-	for _, dm := range dm.Voters {
+	for _, nv := range dm.Voters {
 		var vr VoterResult
-		vr, err = dm.Voter.VoteOnAccess(ctx, c)
+		vr, err = nv.Voter.VoteOnAccess(ctx, c)
 		if err != nil {
 			return
 		}
-		res.VoterResults[dm.Name] = vr
+		res.VoterResults[nv.Name] = vr
 		switch vr {
 		case VoterAgree:
 			agreed++
@@ -0,0 +1,239 @@
+package rocho
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Problem is problem details object as described in RFC 7807.
+//
+// It's sent to client, so it must never contain internal causes of error.
+type Problem struct {
+	Type   string `json:"type,omitempty"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	// Code is stable machine-readable code of error, for instance "invalid_token".
+	Code     string `json:"code"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Header contains additional headers to set on response, for instance Retry-After.
+	Header http.Header `json:"-"`
+}
+
+// ErrorMapper maps error to Problem.
+// It returns nil if it does not know given error.
+type ErrorMapper func(err error) *Problem
+
+// MapError creates ErrorMapper, which maps all errors matching target(according to errors.Is) to given Problem.
+func MapError(target error, p Problem) ErrorMapper {
+	return func(err error) *Problem {
+		if errors.Is(err, target) {
+			res := p
+			return &res
+		}
+		return nil
+	}
+}
+
+// DefaultErrorMappers contains mappings for errors defined by rocho.
+var DefaultErrorMappers = []ErrorMapper{
+	MapError(ErrNoAuthToken, Problem{
+		Status: http.StatusUnauthorized,
+		Code:   "unauthenticated",
+		Detail: "Authentication is required to access this resource.",
+	}),
+	MapError(ErrNoUserData, Problem{
+		Status: http.StatusUnauthorized,
+		Code:   "authentication_failed",
+		Detail: "Authentication failed.",
+	}),
+	// ID token errors may match ErrInvalidCredentials as well, so they have to be mapped first.
+	MapError(ErrInvalidIDToken, Problem{
+		Status: http.StatusUnauthorized,
+		Code:   "invalid_id_token",
+		Detail: "Identity token returned by identity provider is invalid.",
+	}),
+	MapError(ErrInvalidCredentials, Problem{
+		Status: http.StatusUnauthorized,
+		Code:   "invalid_credentials",
+		Detail: "Invalid username or password.",
+	}),
+	MapError(ErrAuthDataNotSupported, Problem{
+		Status: http.StatusBadRequest,
+		Code:   "unsupported_auth_data",
+		Detail: "Given authentication method is not supported.",
+	}),
+	func(err error) *Problem {
+		var target *AuthDataParseError
+		if !errors.As(err, &target) {
+			return nil
+		}
+		if errors.Is(err, ErrUnsupportedContentType) {
+			return &Problem{
+				Status: http.StatusUnsupportedMediaType,
+				Code:   "unsupported_content_type",
+				Detail: "Content type of request is not supported.",
+			}
+		}
+		if errors.Is(err, ErrAuthDataTooLarge) {
+			return &Problem{
+				Status: http.StatusRequestEntityTooLarge,
+				Code:   "auth_data_too_large",
+				Detail: "Authentication data is too large.",
+			}
+		}
+		return &Problem{
+			Status: http.StatusBadRequest,
+			Code:   "invalid_auth_data",
+			Detail: "Authentication data is missing or malformed.",
+		}
+	},
+	func(err error) *Problem {
+		var target *TooManyAttemptsError
+		if !errors.As(err, &target) {
+			return nil
+		}
+		p := &Problem{
+			Status: http.StatusTooManyRequests,
+			Code:   "too_many_attempts",
+			Detail: "Too many authentication attempts.",
+		}
+		if target.RetryAfter > 0 {
+			p.Header = http.Header{}
+			p.Header.Set("Retry-After", strconv.FormatInt(int64((target.RetryAfter+time.Second-1)/time.Second), 10))
+		}
+		return p
+	},
+	// it's wrapped in InvalidAuthTokenError by SessionMiddleware, so it has to be mapped first
+	MapError(ErrInvalidAuthRequest, Problem{
+		Status: http.StatusBadRequest,
+		Code:   "invalid_request",
+		Detail: "Authentication token is passed in invalid way.",
+	}),
+	func(err error) *Problem {
+		var target *InvalidAuthTokenError
+		if !errors.As(err, &target) {
+			return nil
+		}
+		return &Problem{
+			Status: http.StatusUnauthorized,
+			Code:   "invalid_token",
+			Detail: "Authentication token is invalid or expired.",
+		}
+	},
+	func(err error) *Problem {
+		var target *ProviderFiledError
+		if !errors.As(err, &target) {
+			return nil
+		}
+		return &Problem{
+			Status: http.StatusBadGateway,
+			Code:   "provider_failed",
+			Detail: "Identity provider failed to supply user details.",
+		}
+	},
+	func(err error) *Problem {
+		var target *OAuth2StateError
+		if !errors.As(err, &target) {
+			return nil
+		}
+		return &Problem{
+			Status: http.StatusBadRequest,
+			Code:   "oauth2_state_mismatch",
+			Detail: "Authorization flow state does not match. Please try again.",
+		}
+	},
+	func(err error) *Problem {
+		var target *OAuth2StateManagerError
+		if !errors.As(err, &target) {
+			return nil
+		}
+		return &Problem{
+			Status: http.StatusBadRequest,
+			Code:   "oauth2_state_invalid",
+			Detail: "Authorization flow state is missing or expired. Please try again.",
+		}
+	},
+	func(err error) *Problem {
+		var target *OAuth2TokenExchangeError
+		if !errors.As(err, &target) {
+			return nil
+		}
+		return &Problem{
+			Status: http.StatusBadGateway,
+			Code:   "oauth2_exchange_failed",
+			Detail: "Authorization code could not be exchanged with identity provider.",
+		}
+	},
+	func(err error) *Problem {
+		var target *OAuth2IDTokenError
+		if !errors.As(err, &target) {
+			return nil
+		}
+		return &Problem{
+			Status: http.StatusUnauthorized,
+			Code:   "invalid_id_token",
+			Detail: "Identity token returned by identity provider is invalid.",
+		}
+	},
+}
+
+// ErrorRenderer writes response for given error.
+type ErrorRenderer interface {
+	RenderError(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// DefaultErrorRenderer renders errors as application/problem+json responses.
+//
+// Mappers are tried first, then DefaultErrorMappers.
+// Unknown errors are rendered as 500 with "internal_error" code.
+type DefaultErrorRenderer struct {
+	Mappers []ErrorMapper
+}
+
+var defaultErrorRenderer = &DefaultErrorRenderer{}
+
+// Problem returns Problem for given error.
+func (der *DefaultErrorRenderer) Problem(err error) (p Problem) {
+	for _, mappers := range [][]ErrorMapper{der.Mappers, DefaultErrorMappers} {
+		for _, m := range mappers {
+			if res := m(err); res != nil {
+				p = *res
+				break
+			}
+		}
+		if p.Status != 0 {
+			break
+		}
+	}
+
+	if p.Status == 0 {
+		p = Problem{
+			Status: http.StatusInternalServerError,
+			Code:   "internal_error",
+		}
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	return
+}
+
+// RenderError writes Problem for given error to response.
+func (der *DefaultErrorRenderer) RenderError(w http.ResponseWriter, r *http.Request, err error) {
+	p := der.Problem(err)
+	for k, v := range p.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
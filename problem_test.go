@@ -0,0 +1,118 @@
+package rocho
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// idTokenError mimics errors of ID token verifiers, which match both ErrInvalidIDToken and ErrInvalidCredentials.
+type idTokenError struct{}
+
+func (idTokenError) Error() string {
+	return "invalid ID token"
+}
+
+func (idTokenError) Is(target error) bool {
+	return target == ErrInvalidIDToken || target == ErrInvalidCredentials
+}
+
+func TestDefaultErrorRenderer_Problem(t *testing.T) {
+	internal := errors.New("internal cause")
+	for name, tc := range map[string]struct {
+		err    error
+		status int
+		code   string
+	}{
+		"no auth token":            {err: ErrNoAuthToken, status: http.StatusUnauthorized, code: "unauthenticated"},
+		"no user data":             {err: ErrNoUserData, status: http.StatusUnauthorized, code: "authentication_failed"},
+		"invalid credentials":      {err: fmt.Errorf("login: %w", ErrInvalidCredentials), status: http.StatusUnauthorized, code: "invalid_credentials"},
+		"auth data not supported":  {err: ErrAuthDataNotSupported, status: http.StatusBadRequest, code: "unsupported_auth_data"},
+		"unsupported content type": {err: &AuthDataParseError{Err: ErrUnsupportedContentType}, status: http.StatusUnsupportedMediaType, code: "unsupported_content_type"},
+		"auth data too large":      {err: &AuthDataParseError{Err: ErrAuthDataTooLarge}, status: http.StatusRequestEntityTooLarge, code: "auth_data_too_large"},
+		"empty field":              {err: &AuthDataParseError{Field: "username", Err: ErrEmptyField}, status: http.StatusBadRequest, code: "invalid_auth_data"},
+		"too many attempts":        {err: &TooManyAttemptsError{}, status: http.StatusTooManyRequests, code: "too_many_attempts"},
+		"invalid auth token":       {err: &InvalidAuthTokenError{Err: internal}, status: http.StatusUnauthorized, code: "invalid_token"},
+		"invalid auth request":     {err: &InvalidAuthTokenError{Err: fmt.Errorf("bearer: %w", ErrInvalidAuthRequest)}, status: http.StatusBadRequest, code: "invalid_request"},
+		"provider failed":          {err: &ProviderFiledError{Err: internal}, status: http.StatusBadGateway, code: "provider_failed"},
+		"OAuth2 state mismatch":    {err: &OAuth2StateError{}, status: http.StatusBadRequest, code: "oauth2_state_mismatch"},
+		"OAuth2 state invalid":     {err: &OAuth2StateManagerError{Err: ErrInvalidOAuth2State}, status: http.StatusBadRequest, code: "oauth2_state_invalid"},
+		"OAuth2 exchange failed":   {err: &OAuth2TokenExchangeError{Err: internal}, status: http.StatusBadGateway, code: "oauth2_exchange_failed"},
+		"OAuth2 ID token":          {err: &OAuth2IDTokenError{Err: ErrNoIDToken}, status: http.StatusUnauthorized, code: "invalid_id_token"},
+		"invalid ID token":         {err: fmt.Errorf("provider: %w", idTokenError{}), status: http.StatusUnauthorized, code: "invalid_id_token"},
+		"unknown":                  {err: internal, status: http.StatusInternalServerError, code: "internal_error"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := (&DefaultErrorRenderer{}).Problem(tc.err)
+			if p.Status != tc.status || p.Code != tc.code {
+				t.Errorf("expected %d %q, got %d %q", tc.status, tc.code, p.Status, p.Code)
+			}
+			if p.Title != http.StatusText(tc.status) || p.Type != "about:blank" {
+				t.Errorf("expected default title and type, got %q and %q", p.Title, p.Type)
+			}
+		})
+	}
+}
+
+func TestDefaultErrorRenderer_CustomMappers(t *testing.T) {
+	der := &DefaultErrorRenderer{
+		Mappers: []ErrorMapper{
+			MapError(ErrInvalidCredentials, Problem{
+				Type:   "https://example.com/problems/login",
+				Title:  "Login failed",
+				Status: http.StatusForbidden,
+				Code:   "login_failed",
+			}),
+		},
+	}
+
+	p := der.Problem(ErrInvalidCredentials)
+	if p.Status != http.StatusForbidden || p.Code != "login_failed" || p.Title != "Login failed" || p.Type != "https://example.com/problems/login" {
+		t.Errorf("expected custom mapper to take precedence, got %+v", p)
+	}
+
+	p = der.Problem(ErrNoAuthToken)
+	if p.Code != "unauthenticated" {
+		t.Errorf("expected fallback to default mappers, got %+v", p)
+	}
+}
+
+func TestDefaultErrorRenderer_RenderError(t *testing.T) {
+	w := httptest.NewRecorder()
+	defaultErrorRenderer.RenderError(w, httptest.NewRequest("POST", "/login", nil), &TooManyAttemptsError{RetryAfter: 1500 * time.Millisecond})
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", w.Code)
+	}
+	for name, value := range map[string]string{
+		"Content-Type":           "application/problem+json",
+		"X-Content-Type-Options": "nosniff",
+		"Retry-After":            "2",
+	} {
+		if w.Header().Get(name) != value {
+			t.Errorf("expected %s %q, got %q", name, value, w.Header().Get(name))
+		}
+	}
+
+	var body map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body["code"] != "too_many_attempts" || body["status"] != float64(http.StatusTooManyRequests) || body["type"] != "about:blank" {
+		t.Errorf("unexpected body %v", body)
+	}
+}
+
+func TestDefaultErrorRenderer_HidesInternalCause(t *testing.T) {
+	w := httptest.NewRecorder()
+	defaultErrorRenderer.RenderError(w, httptest.NewRequest("GET", "/", nil), &ProviderFiledError{Err: errors.New("secret upstream failure")})
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("expected internal cause not to be rendered, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,13 @@
+package refresh
+
+import "errors"
+
+// ErrTokenNotFound is returned by Store when there is no record for given refresh token.
+var ErrTokenNotFound = errors.New("rocho/refresh: Refresh token not found")
+
+// ErrTokenReused is returned when refresh token, which was already rotated, is used again.
+// Whole token family is revoked in that case.
+var ErrTokenReused = errors.New("rocho/refresh: Refresh token was already used")
+
+// ErrInvalidToken is returned when refresh token can't be used.
+var ErrInvalidToken = errors.New("rocho/refresh: Invalid refresh token")
@@ -0,0 +1,83 @@
+package refresh
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/teawithsand/rocho"
+)
+
+// Response is JSON body written by Handler.
+type Response = rocho.TokenResponse
+
+type request struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+const maxRequestSize = 64 * 1024
+
+// Handler exchanges refresh token for new pair of access and refresh tokens.
+//
+// Refresh token is read from "refresh_token" field of form-encoded or JSON body.
+// Access token is serialized using AuthEngine.
+type Handler struct {
+	Manager    *Manager
+	AuthEngine rocho.AuthEngine
+
+	// ErrorHandler is called when refresh fails. If nil, rocho.DefaultErrorRenderer is used.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+func (handler *Handler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if handler.ErrorHandler != nil {
+		handler.ErrorHandler(w, r, err)
+		return
+	}
+	if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenReused) {
+		err = &rocho.InvalidAuthTokenError{Err: err}
+	}
+	(&rocho.DefaultErrorRenderer{}).RenderError(w, r, err)
+}
+
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	var req request
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&req)
+		if err != nil {
+			handler.handleError(w, r, &rocho.InvalidAuthTokenError{Err: err})
+			return
+		}
+	} else {
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+		req.RefreshToken = r.PostFormValue("refresh_token")
+	}
+	if req.RefreshToken == "" {
+		handler.handleError(w, r, rocho.ErrNoAuthToken)
+		return
+	}
+
+	at, refreshToken, err := handler.Manager.Refresh(ctx, []byte(req.RefreshToken))
+	if err != nil {
+		handler.handleError(w, r, err)
+		return
+	}
+
+	accessToken, err := handler.AuthEngine.SerializerAuthToken(ctx, at)
+	if err != nil {
+		handler.handleError(w, r, err)
+		return
+	}
+
+	_ = rocho.WriteTokenResponse(w, at, accessToken, refreshToken, handler.Manager.now())
+}
@@ -0,0 +1,251 @@
+// Package refresh implements issuance and rotation of refresh tokens.
+//
+// Each refresh token can be used only once. Using it returns new access token and new refresh token from
+// the same family. Using already rotated refresh token revokes whole family, since it means that token was stolen.
+package refresh
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+// Token is AuthToken of refresh token, which is sent to client.
+// It's serialized using any rocho.AuthTokenSerializer, so serializer must create *Token during deserialization.
+type Token struct {
+	ID       string `json:"jti"`
+	FamilyID string `json:"fam"`
+	Exp      int64  `json:"exp,omitempty"`
+}
+
+// ExpiresAt implements rocho.ExpiringAuthToken.
+func (t *Token) ExpiresAt() time.Time {
+	if t.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(t.Exp, 0)
+}
+
+// Manager issues and rotates refresh tokens.
+type Manager struct {
+	Store Store
+
+	// Serializer serializes Tokens.
+	Serializer rocho.AuthTokenSerializer
+	// SessionEngine, if set, is used for deserialization of Tokens instead of Serializer,
+	// so they go through it's validation pipeline.
+	SessionEngine rocho.SessionEngine
+
+	// TTL is lifetime of single refresh token. Zero means no expiration.
+	TTL time.Duration
+
+	// Renew, if set, is called with access token stored during issuance in order to create new one during refresh,
+	// for instance with updated user data.
+	Renew func(ctx context.Context, at rocho.AuthToken) (rocho.AuthToken, error)
+
+	// Events receives rocho.EventTokenRefreshed events and rocho.EventTokenRevoked events on reuse detection.
+	// It may be nil.
+	Events *rocho.EventBus
+
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+func (m *Manager) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+func randomID() (id string, err error) {
+	raw := make([]byte, 32)
+	_, err = io.ReadFull(rand.Reader, raw)
+	if err != nil {
+		return
+	}
+	id = base64.RawURLEncoding.EncodeToString(raw)
+	return
+}
+
+// snapshot returns copy of AuthToken, which does not share memory with it, so stripping secret info
+// from original during serialization does not affect copy.
+// Copy is made by JSON round trip into new value of the same type.
+func snapshot(at rocho.AuthToken) (cp rocho.AuthToken, err error) {
+	if at == nil {
+		return
+	}
+	raw, err := json.Marshal(at)
+	if err != nil {
+		return
+	}
+
+	t := reflect.TypeOf(at)
+	if t.Kind() == reflect.Ptr {
+		v := reflect.New(t.Elem())
+		err = json.Unmarshal(raw, v.Interface())
+		if err != nil {
+			return
+		}
+		cp = v.Interface()
+	} else {
+		v := reflect.New(t)
+		err = json.Unmarshal(raw, v.Interface())
+		if err != nil {
+			return
+		}
+		cp = v.Elem().Interface()
+	}
+	return
+}
+
+// Issue creates new refresh token family for given access token and returns serialized refresh token.
+// Copy of access token is stored, so it may be serialized, which strips secret info, before or after Issue.
+func (m *Manager) Issue(ctx context.Context, at rocho.AuthToken) (data []byte, err error) {
+	familyID, err := randomID()
+	if err != nil {
+		return
+	}
+	data, err = m.issue(ctx, familyID, at)
+	return
+}
+
+func (m *Manager) issue(ctx context.Context, familyID string, at rocho.AuthToken) (data []byte, err error) {
+	id, err := randomID()
+	if err != nil {
+		return
+	}
+
+	// caller may strip secret info from at in place while serializing it as access token
+	stored, err := snapshot(at)
+	if err != nil {
+		return
+	}
+
+	now := m.now()
+	rec := &Record{
+		ID:        id,
+		FamilyID:  familyID,
+		AuthToken: stored,
+		IssuedAt:  now,
+	}
+	t := &Token{
+		ID:       id,
+		FamilyID: familyID,
+	}
+	if m.TTL > 0 {
+		rec.Expiry = now.Add(m.TTL)
+		t.Exp = rec.Expiry.Unix()
+	}
+
+	err = m.Store.SaveRecord(ctx, rec)
+	if err != nil {
+		return
+	}
+
+	data, err = m.Serializer.SerializeAuthToken(ctx, t)
+	return
+}
+
+func (m *Manager) loadToken(ctx context.Context, data []byte) (t *Token, err error) {
+	var at rocho.AuthToken
+	if m.SessionEngine != nil {
+		at, err = m.SessionEngine.GetRawAuthToken(ctx, data)
+	} else {
+		at, err = m.Serializer.DeserializeAuthToken(ctx, data)
+	}
+	if err != nil {
+		return
+	}
+
+	t, ok := at.(*Token)
+	if !ok || t.ID == "" {
+		err = ErrInvalidToken
+	}
+	return
+}
+
+// Refresh rotates given refresh token.
+// It returns access token to serialize and new refresh token.
+//
+// If refresh token was already used, whole family is revoked and ErrTokenReused is returned.
+func (m *Manager) Refresh(ctx context.Context, data []byte) (at rocho.AuthToken, refreshToken []byte, err error) {
+	t, err := m.loadToken(ctx, data)
+	if err != nil {
+		return
+	}
+
+	rec, err := m.Store.LoadRecord(ctx, t.ID)
+	if errors.Is(err, ErrTokenNotFound) {
+		err = ErrInvalidToken
+		return
+	} else if err != nil {
+		return
+	}
+	if rec.FamilyID != t.FamilyID || (!rec.Expiry.IsZero() && !m.now().Before(rec.Expiry)) {
+		err = ErrInvalidToken
+		return
+	}
+
+	err = m.Store.MarkUsed(ctx, rec.ID)
+	if errors.Is(err, ErrTokenReused) {
+		rerr := m.Store.RevokeFamily(ctx, rec.FamilyID)
+		if rerr != nil {
+			err = rerr
+			return
+		}
+		m.Events.Emit(ctx, m.event(rocho.EventTokenRevoked, rec.AuthToken, err))
+		return
+	} else if err != nil {
+		return
+	}
+
+	// never hand out stored AuthToken itself, since it's stripped in place during serialization
+	at, err = snapshot(rec.AuthToken)
+	if err != nil {
+		return
+	}
+	if m.Renew != nil {
+		at, err = m.Renew(ctx, at)
+		if err != nil {
+			at = nil
+			return
+		}
+	}
+
+	refreshToken, err = m.issue(ctx, rec.FamilyID, at)
+	if err != nil {
+		at = nil
+		return
+	}
+
+	m.Events.Emit(ctx, m.event(rocho.EventTokenRefreshed, at, nil))
+	return
+}
+
+func (m *Manager) event(et rocho.EventType, at rocho.AuthToken, err error) (e rocho.Event) {
+	e.Type = et
+	e.Err = err
+	if uat, ok := at.(rocho.UserAuthToken); ok {
+		e.UserID = uat.UserID()
+	}
+	return
+}
+
+// RevokeAuthToken revokes family of given *Token.
+func (m *Manager) RevokeAuthToken(ctx context.Context, at rocho.AuthToken) (err error) {
+	t, ok := at.(*Token)
+	if !ok {
+		err = rocho.ErrAuthDataNotSupported
+		return
+	}
+	err = m.Store.RevokeFamily(ctx, t.FamilyID)
+	return
+}
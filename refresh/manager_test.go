@@ -0,0 +1,142 @@
+package refresh
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/teawithsand/rocho"
+	"github.com/teawithsand/rocho/jwt"
+)
+
+// stubAuthEngine authenticates every request as the same user and serializes AuthTokens to JSON.
+type stubAuthEngine struct{}
+
+func (stubAuthEngine) AuthenticateRequest(ctx context.Context, r *http.Request) (at rocho.AuthToken, err error) {
+	at = map[string]interface{}{"sub": "user"}
+	return
+}
+
+func (stubAuthEngine) AuthenticateAuthData(ctx context.Context, ad rocho.AuthData) (at rocho.AuthToken, err error) {
+	err = rocho.ErrAuthDataNotSupported
+	return
+}
+
+func (stubAuthEngine) SerializeAuthTokenToResponse(ctx context.Context, at rocho.AuthToken, w http.ResponseWriter) (err error) {
+	err = errors.New("not used")
+	return
+}
+
+func (stubAuthEngine) SerializerAuthToken(ctx context.Context, at rocho.AuthToken) (data []byte, err error) {
+	data, err = json.Marshal(at)
+	return
+}
+
+func newTestManager() *Manager {
+	return &Manager{
+		Store: &MemoryStore{},
+		Serializer: &jwt.Serializer{
+			Method: &jwt.HMAC{Hash: crypto.SHA256, Key: []byte("0123456789abcdef0123456789abcdef")},
+			NewAuthToken: func() rocho.AuthToken {
+				return &Token{}
+			},
+		},
+	}
+}
+
+func postTokens(t *testing.T, h http.Handler, body url.Values) (res rocho.TokenResponse, code int) {
+	t.Helper()
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	code = w.Code
+	if code == http.StatusOK {
+		err := json.Unmarshal(w.Body.Bytes(), &res)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return
+}
+
+func TestLoginReturnsRefreshToken(t *testing.T) {
+	m := newTestManager()
+	login := &rocho.LoginHandler{
+		AuthEngine:         stubAuthEngine{},
+		RefreshTokenIssuer: m,
+	}
+	refresh := &Handler{
+		Manager:    m,
+		AuthEngine: stubAuthEngine{},
+	}
+
+	res, code := postTokens(t, login, url.Values{})
+	if code != http.StatusOK {
+		t.Fatalf("expected login to succeed, got %d", code)
+	}
+	if res.AccessToken != `{"sub":"user"}` || res.RefreshToken == "" {
+		t.Fatalf("expected access and refresh token, got %+v", res)
+	}
+
+	rotated, code := postTokens(t, refresh, url.Values{"refresh_token": {res.RefreshToken}})
+	if code != http.StatusOK {
+		t.Fatalf("expected refresh to succeed, got %d", code)
+	}
+	if rotated.AccessToken != res.AccessToken || rotated.RefreshToken == "" || rotated.RefreshToken == res.RefreshToken {
+		t.Fatalf("expected rotated refresh token, got %+v", rotated)
+	}
+
+	// reuse of rotated token revokes whole family
+	_, code = postTokens(t, refresh, url.Values{"refresh_token": {res.RefreshToken}})
+	if code != http.StatusUnauthorized {
+		t.Fatalf("expected reused token to be rejected, got %d", code)
+	}
+	_, code = postTokens(t, refresh, url.Values{"refresh_token": {rotated.RefreshToken}})
+	if code != http.StatusUnauthorized {
+		t.Fatalf("expected family to be revoked, got %d", code)
+	}
+}
+
+type secretToken struct {
+	Sub    string `json:"sub"`
+	Secret string `json:"secret"`
+}
+
+func (st *secretToken) StripSecretInfo() {
+	st.Secret = ""
+}
+
+func TestRefreshKeepsSecretInfo(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager()
+
+	at := &secretToken{Sub: "user", Secret: "secret"}
+	data, err := m.Issue(ctx, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// access token is serialized after refresh token is issued
+	at.StripSecretInfo()
+
+	for i := 0; i < 2; i++ {
+		var rat rocho.AuthToken
+		rat, data, err = m.Refresh(ctx, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st := rat.(*secretToken)
+		if st.Sub != "user" || st.Secret != "secret" {
+			t.Fatalf("expected secret info to survive refresh, got %+v", st)
+		}
+		st.StripSecretInfo()
+	}
+}
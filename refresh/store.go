@@ -0,0 +1,122 @@
+package refresh
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+// Record is server-side state of single refresh token.
+type Record struct {
+	ID       string
+	FamilyID string
+
+	// AuthToken is copy of access token, which is reissued on refresh.
+	AuthToken rocho.AuthToken
+
+	IssuedAt time.Time
+	Expiry   time.Time
+	Used     bool
+}
+
+// Store keeps refresh token records.
+type Store interface {
+	SaveRecord(ctx context.Context, rec *Record) (err error)
+	// LoadRecord returns ErrTokenNotFound if there is no such record.
+	LoadRecord(ctx context.Context, id string) (rec *Record, err error)
+	// MarkUsed atomically marks record as used. It returns ErrTokenReused if record was already used.
+	MarkUsed(ctx context.Context, id string) (err error)
+	// RevokeFamily removes all records of given family.
+	RevokeFamily(ctx context.Context, familyID string) (err error)
+}
+
+// MemoryStore is Store, which keeps records in memory.
+// Expired records are evicted periodically during saves.
+type MemoryStore struct {
+	// CleanupInterval is minimal time between evictions of expired records. If zero, one minute is used.
+	CleanupInterval time.Duration
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	lock        sync.Mutex
+	records     map[string]Record
+	lastCleanup time.Time
+}
+
+func (ms *MemoryStore) now() time.Time {
+	if ms.Now != nil {
+		return ms.Now()
+	}
+	return time.Now()
+}
+
+func (ms *MemoryStore) SaveRecord(ctx context.Context, rec *Record) (err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	if ms.records == nil {
+		ms.records = map[string]Record{}
+	}
+
+	ms.records[rec.ID] = *rec
+
+	now := ms.now()
+	interval := ms.CleanupInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if now.Sub(ms.lastCleanup) >= interval {
+		ms.lastCleanup = now
+		for id, stored := range ms.records {
+			if !stored.Expiry.IsZero() && !now.Before(stored.Expiry) {
+				delete(ms.records, id)
+			}
+		}
+	}
+	return
+}
+
+func (ms *MemoryStore) LoadRecord(ctx context.Context, id string) (rec *Record, err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	stored, ok := ms.records[id]
+	if !ok {
+		err = ErrTokenNotFound
+		return
+	}
+	rec = &stored
+	return
+}
+
+func (ms *MemoryStore) MarkUsed(ctx context.Context, id string) (err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	stored, ok := ms.records[id]
+	if !ok {
+		err = ErrTokenNotFound
+		return
+	}
+	if stored.Used {
+		err = ErrTokenReused
+		return
+	}
+	stored.Used = true
+	ms.records[id] = stored
+	return
+}
+
+func (ms *MemoryStore) RevokeFamily(ctx context.Context, familyID string) (err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	for id, stored := range ms.records {
+		if stored.FamilyID == familyID {
+			delete(ms.records, id)
+		}
+	}
+	return
+}
@@ -0,0 +1,6 @@
+package revocation
+
+import "errors"
+
+// ErrTokenRevoked is returned by Validator when AuthToken was revoked.
+var ErrTokenRevoked = errors.New("rocho/revocation: Token has been revoked")
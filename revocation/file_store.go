@@ -0,0 +1,161 @@
+package revocation
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore is Store, which keeps revoked tokens in single JSON file.
+// File is loaded on first use and rewritten atomically on each revocation.
+// Entries of expired tokens are evicted periodically during revocations.
+//
+// It's intended for single process. Many processes sharing same file won't see each other's revocations
+// until restart.
+type FileStore struct {
+	Path string
+
+	// CleanupInterval is minimal time between evictions of expired entries. If zero, one minute is used.
+	CleanupInterval time.Duration
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	lock        sync.RWMutex
+	loaded      bool
+	list        revocationList
+	lastCleanup time.Time
+}
+
+func (fs *FileStore) now() time.Time {
+	if fs.Now != nil {
+		return fs.Now()
+	}
+	return time.Now()
+}
+
+// load must be called with write lock held.
+func (fs *FileStore) load() (err error) {
+	if fs.loaded {
+		return
+	}
+
+	data, err := ioutil.ReadFile(fs.Path)
+	if os.IsNotExist(err) {
+		err = nil
+		fs.loaded = true
+		return
+	} else if err != nil {
+		return
+	}
+
+	var list revocationList
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return
+	}
+	fs.list = list
+	fs.loaded = true
+	return
+}
+
+// save must be called with write lock held.
+func (fs *FileStore) save() (err error) {
+	data, err := json.Marshal(&fs.list)
+	if err != nil {
+		return
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(fs.Path), ".tmp-")
+	if err != nil {
+		return
+	}
+	tmpName := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return
+	}
+
+	err = os.Rename(tmpName, fs.Path)
+	if err != nil {
+		_ = os.Remove(tmpName)
+	}
+	return
+}
+
+func (fs *FileStore) ensureLoaded() (err error) {
+	fs.lock.RLock()
+	loaded := fs.loaded
+	fs.lock.RUnlock()
+	if loaded {
+		return
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	err = fs.load()
+	return
+}
+
+func (fs *FileStore) RevokeToken(ctx context.Context, tokenID string, expiry time.Time) (err error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	err = fs.load()
+	if err != nil {
+		return
+	}
+	fs.list.revokeToken(tokenID, expiry)
+	fs.list.cleanup(fs.now(), fs.CleanupInterval, &fs.lastCleanup)
+	err = fs.save()
+	return
+}
+
+func (fs *FileStore) RevokeUserTokens(ctx context.Context, userID string, before time.Time) (err error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	err = fs.load()
+	if err != nil {
+		return
+	}
+	fs.list.revokeUserTokens(userID, before)
+	err = fs.save()
+	return
+}
+
+func (fs *FileStore) IsTokenRevoked(ctx context.Context, tokenID string) (revoked bool, err error) {
+	err = fs.ensureLoaded()
+	if err != nil {
+		return
+	}
+
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	_, revoked = fs.list.Tokens[tokenID]
+	return
+}
+
+func (fs *FileStore) UserTokensRevokedBefore(ctx context.Context, userID string) (before time.Time, err error) {
+	err = fs.ensureLoaded()
+	if err != nil {
+		return
+	}
+
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	before = fs.list.Users[userID]
+	return
+}
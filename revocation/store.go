@@ -0,0 +1,114 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps revoked tokens.
+type Store interface {
+	// RevokeToken revokes single token with given ID.
+	// Entry may be dropped after expiry, since token is not valid anyway then. Zero expiry means keeping it forever.
+	RevokeToken(ctx context.Context, tokenID string, expiry time.Time) (err error)
+	// RevokeUserTokens revokes all tokens of given user issued before given time.
+	RevokeUserTokens(ctx context.Context, userID string, before time.Time) (err error)
+
+	IsTokenRevoked(ctx context.Context, tokenID string) (revoked bool, err error)
+	// UserTokensRevokedBefore returns time before which all tokens of user are revoked.
+	// Returns zero time if there is none.
+	UserTokensRevokedBefore(ctx context.Context, userID string) (before time.Time, err error)
+}
+
+// revocationList is data kept by both MemoryStore and FileStore.
+type revocationList struct {
+	Tokens map[string]time.Time `json:"tokens"`
+	Users  map[string]time.Time `json:"users"`
+}
+
+func (rl *revocationList) revokeToken(tokenID string, expiry time.Time) {
+	if rl.Tokens == nil {
+		rl.Tokens = map[string]time.Time{}
+	}
+	rl.Tokens[tokenID] = expiry
+}
+
+// cleanup drops entries of tokens, which have expired anyway.
+// It's done at most once per interval, since it has to iterate over all entries.
+func (rl *revocationList) cleanup(now time.Time, interval time.Duration, lastCleanup *time.Time) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if now.Sub(*lastCleanup) < interval {
+		return
+	}
+	*lastCleanup = now
+
+	for id, exp := range rl.Tokens {
+		if !exp.IsZero() && exp.Before(now) {
+			delete(rl.Tokens, id)
+		}
+	}
+}
+
+func (rl *revocationList) revokeUserTokens(userID string, before time.Time) {
+	if rl.Users == nil {
+		rl.Users = map[string]time.Time{}
+	}
+	if before.After(rl.Users[userID]) {
+		rl.Users[userID] = before
+	}
+}
+
+// MemoryStore is Store, which keeps revoked tokens in memory.
+// Entries of expired tokens are evicted periodically during revocations.
+type MemoryStore struct {
+	// CleanupInterval is minimal time between evictions of expired entries. If zero, one minute is used.
+	CleanupInterval time.Duration
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	lock        sync.RWMutex
+	list        revocationList
+	lastCleanup time.Time
+}
+
+func (ms *MemoryStore) now() time.Time {
+	if ms.Now != nil {
+		return ms.Now()
+	}
+	return time.Now()
+}
+
+func (ms *MemoryStore) RevokeToken(ctx context.Context, tokenID string, expiry time.Time) (err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	ms.list.revokeToken(tokenID, expiry)
+	ms.list.cleanup(ms.now(), ms.CleanupInterval, &ms.lastCleanup)
+	return
+}
+
+func (ms *MemoryStore) RevokeUserTokens(ctx context.Context, userID string, before time.Time) (err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	ms.list.revokeUserTokens(userID, before)
+	return
+}
+
+func (ms *MemoryStore) IsTokenRevoked(ctx context.Context, tokenID string) (revoked bool, err error) {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+
+	_, revoked = ms.list.Tokens[tokenID]
+	return
+}
+
+func (ms *MemoryStore) UserTokensRevokedBefore(ctx context.Context, userID string) (before time.Time, err error) {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+
+	before = ms.list.Users[userID]
+	return
+}
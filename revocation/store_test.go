@@ -0,0 +1,264 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+// identifiedToken is token with ID, which may expire.
+type identifiedToken struct {
+	id  string
+	exp time.Time
+}
+
+func (t *identifiedToken) TokenID() string {
+	return t.id
+}
+
+func (t *identifiedToken) ExpiresAt() time.Time {
+	return t.exp
+}
+
+func newTestDir(t *testing.T) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "rocho-revocation-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup = func() {
+		_ = os.RemoveAll(dir)
+	}
+	return
+}
+
+// storeFactories create stores of each kind, which use given clock. Each FileStore gets it's own file in dir.
+func storeFactories(dir string) map[string]func(now func() time.Time) Store {
+	i := 0
+	return map[string]func(now func() time.Time) Store{
+		"memory": func(now func() time.Time) Store {
+			return &MemoryStore{Now: now}
+		},
+		"file": func(now func() time.Time) Store {
+			i++
+			return &FileStore{Path: filepath.Join(dir, fmt.Sprintf("revoked-%d.json", i)), Now: now}
+		},
+	}
+}
+
+func TestStore_RevokeToken(t *testing.T) {
+	dir, cleanup := newTestDir(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Now()
+
+	for name, newStore := range storeFactories(dir) {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(func() time.Time {
+				return now
+			})
+
+			err := s.RevokeToken(ctx, "revoked", now.Add(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for id, expected := range map[string]bool{"revoked": true, "other": false} {
+				revoked, err := s.IsTokenRevoked(ctx, id)
+				if err != nil || revoked != expected {
+					t.Errorf("token %q: expected revoked %v, got %v, %v", id, expected, revoked, err)
+				}
+			}
+		})
+	}
+}
+
+func TestStore_RevokeUserTokens(t *testing.T) {
+	dir, cleanup := newTestDir(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Now()
+
+	for name, newStore := range storeFactories(dir) {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(nil)
+
+			before, err := s.UserTokensRevokedBefore(ctx, "user")
+			if err != nil || !before.IsZero() {
+				t.Errorf("expected no revocation, got %v, %v", before, err)
+			}
+
+			for _, revokedAt := range []time.Time{now, now.Add(-time.Hour)} {
+				err = s.RevokeUserTokens(ctx, "user", revokedAt)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			before, err = s.UserTokensRevokedBefore(ctx, "user")
+			if err != nil || !before.Equal(now) {
+				t.Errorf("expected latest revocation to be kept, got %v, %v", before, err)
+			}
+		})
+	}
+}
+
+func TestStore_Cleanup(t *testing.T) {
+	dir, cleanup := newTestDir(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for name, newStore := range storeFactories(dir) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			s := newStore(func() time.Time {
+				return now
+			})
+			isRevoked := func(id string) bool {
+				revoked, err := s.IsTokenRevoked(ctx, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return revoked
+			}
+
+			for id, expiry := range map[string]time.Time{
+				"expiring": now.Add(time.Second),
+				"forever":  {},
+			} {
+				err := s.RevokeToken(ctx, id, expiry)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// expired entries are not evicted more often than once per cleanup interval
+			now = now.Add(30 * time.Second)
+			err := s.RevokeToken(ctx, "first", time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !isRevoked("expiring") {
+				t.Error("expected expired entry to be kept until cleanup interval passes")
+			}
+
+			now = now.Add(time.Minute)
+			err = s.RevokeToken(ctx, "second", time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isRevoked("expiring") {
+				t.Error("expected expired entry to be evicted")
+			}
+			for _, id := range []string{"forever", "first", "second"} {
+				if !isRevoked(id) {
+					t.Errorf("expected %q to be kept", id)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStore_Persistence(t *testing.T) {
+	dir, cleanup := newTestDir(t)
+	defer cleanup()
+	ctx := context.Background()
+	path := filepath.Join(dir, "revoked.json")
+	now := time.Now()
+
+	s := &FileStore{Path: path}
+	err := s.RevokeToken(ctx, "token", now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.RevokeUserTokens(ctx, "user", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := &FileStore{Path: path}
+	revoked, err := reopened.IsTokenRevoked(ctx, "token")
+	if err != nil || !revoked {
+		t.Errorf("expected token revocation to be persisted, got %v, %v", revoked, err)
+	}
+	before, err := reopened.UserTokensRevokedBefore(ctx, "user")
+	if err != nil || !before.Equal(now) {
+		t.Errorf("expected user revocation to be persisted, got %v, %v", before, err)
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left, got %d files", len(entries))
+	}
+}
+
+func TestFileStore_MalformedFile(t *testing.T) {
+	dir, cleanup := newTestDir(t)
+	defer cleanup()
+	ctx := context.Background()
+	path := filepath.Join(dir, "revoked.json")
+
+	err := ioutil.WriteFile(path, []byte("{"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &FileStore{Path: path}
+	_, err = s.IsTokenRevoked(ctx, "token")
+	if err == nil {
+		t.Error("expected malformed file to be reported")
+	}
+	err = s.RevokeToken(ctx, "token", time.Time{})
+	if err == nil {
+		t.Error("expected malformed file not to be overwritten")
+	}
+}
+
+func TestRevoker_RevokeAuthToken(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := &MemoryStore{}
+	r := &Revoker{Store: store}
+	v := &Validator{Store: store}
+
+	revoked := &identifiedToken{id: "revoked", exp: now.Add(time.Hour)}
+	err := r.RevokeAuthToken(ctx, revoked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.list.Tokens["revoked"] != revoked.exp {
+		t.Errorf("expected entry to be kept until token expires, got %v", store.list.Tokens["revoked"])
+	}
+
+	for name, tc := range map[string]struct {
+		at  rocho.AuthToken
+		err error
+	}{
+		"revoked":        {at: revoked},
+		"other":          {at: &identifiedToken{id: "other"}},
+		"no ID":          {at: &identifiedToken{}, err: rocho.ErrAuthDataNotSupported},
+		"not identified": {at: "token", err: rocho.ErrAuthDataNotSupported},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if tc.err != nil {
+				err := r.RevokeAuthToken(ctx, tc.at)
+				if !errors.Is(err, tc.err) {
+					t.Errorf("expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			err := v.ValidatePreRefill(ctx, tc.at)
+			if errors.Is(err, ErrTokenRevoked) != (tc.at == revoked) {
+				t.Errorf("unexpected validation result %v", err)
+			}
+		})
+	}
+}
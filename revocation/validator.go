@@ -0,0 +1,119 @@
+// Package revocation allows invalidating stateless tokens before they expire.
+//
+// Tokens can be revoked one by one, using their ID, or all tokens of given user issued before some time can be revoked,
+// for instance after password change.
+package revocation
+
+import (
+	"context"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+// Validator implements rocho.AuthTokenValidator, which rejects revoked tokens.
+//
+// Tokens implementing rocho.IdentifiedAuthToken are checked by ID.
+// Tokens implementing rocho.UserAuthToken are checked against revocations of all user's tokens. If such revocation
+// exists, token which does not implement rocho.IssuedAuthToken or has no issue time is considered revoked.
+//
+// Issue times are compared with second precision. Token issued in the same second as revocation of user's tokens
+// is not revoked, so user who logs in right after changing password is not logged out.
+type Validator struct {
+	Store Store
+}
+
+// ValidatePreRefill returns ErrTokenRevoked if token was revoked.
+func (v *Validator) ValidatePreRefill(ctx context.Context, at rocho.AuthToken) (err error) {
+	if iat, ok := at.(rocho.IdentifiedAuthToken); ok {
+		if id := iat.TokenID(); id != "" {
+			var revoked bool
+			revoked, err = v.Store.IsTokenRevoked(ctx, id)
+			if err != nil {
+				return
+			}
+			if revoked {
+				err = ErrTokenRevoked
+				return
+			}
+		}
+	}
+
+	if uat, ok := at.(rocho.UserAuthToken); ok {
+		if userID := uat.UserID(); userID != "" {
+			var before time.Time
+			before, err = v.Store.UserTokensRevokedBefore(ctx, userID)
+			if err != nil || before.IsZero() {
+				return
+			}
+
+			var issuedAt time.Time
+			if iat, ok := at.(rocho.IssuedAuthToken); ok {
+				issuedAt = iat.IssuedAt()
+			}
+			// issue times of JWT and PASETO have second precision, so revocation time is compared at it
+			if issuedAt.IsZero() || issuedAt.Unix() < before.Unix() {
+				err = ErrTokenRevoked
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// ValidateAfterRefill does nothing. Revocation is checked before refill, so no work is done for revoked tokens.
+func (v *Validator) ValidateAfterRefill(ctx context.Context, at rocho.AuthToken) (err error) {
+	return
+}
+
+// Revoker implements rocho.AuthTokenRevoker using Store.
+type Revoker struct {
+	Store Store
+
+	// Events receives rocho.EventTokenRevoked events from RevokeUser. It may be nil.
+	// RevokeAuthToken does not emit events, since it's caller's job, like rocho.LogoutHandler does.
+	Events *rocho.EventBus
+
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+func (r *Revoker) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// RevokeAuthToken revokes token implementing rocho.IdentifiedAuthToken.
+// If token implements rocho.ExpiringAuthToken, revocation entry is kept only until it expires.
+func (r *Revoker) RevokeAuthToken(ctx context.Context, at rocho.AuthToken) (err error) {
+	iat, ok := at.(rocho.IdentifiedAuthToken)
+	if !ok || iat.TokenID() == "" {
+		err = rocho.ErrAuthDataNotSupported
+		return
+	}
+
+	var expiry time.Time
+	if eat, ok := at.(rocho.ExpiringAuthToken); ok {
+		expiry = eat.ExpiresAt()
+	}
+
+	err = r.Store.RevokeToken(ctx, iat.TokenID(), expiry)
+	return
+}
+
+// RevokeUser revokes all tokens of given user issued until now, for instance to log out all devices.
+func (r *Revoker) RevokeUser(ctx context.Context, userID string) (err error) {
+	err = r.Store.RevokeUserTokens(ctx, userID, r.now())
+	if err != nil {
+		return
+	}
+
+	r.Events.Emit(ctx, rocho.Event{
+		Type:   rocho.EventTokenRevoked,
+		UserID: userID,
+	})
+	return
+}
@@ -0,0 +1,50 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type testToken struct {
+	sub string
+	iat int64
+}
+
+func (t *testToken) UserID() string {
+	return t.sub
+}
+
+func (t *testToken) IssuedAt() time.Time {
+	return time.Unix(t.iat, 0)
+}
+
+func TestValidator_UserRevocationPrecision(t *testing.T) {
+	ctx := context.Background()
+	revokedAt := time.Unix(1000, 500*int64(time.Millisecond))
+	store := &MemoryStore{}
+	r := &Revoker{
+		Store: store,
+		Now: func() time.Time {
+			return revokedAt
+		},
+	}
+	v := &Validator{Store: store}
+
+	err := r.RevokeUser(ctx, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for iat, revoked := range map[int64]bool{
+		999:  true,
+		1000: false,
+		1001: false,
+	} {
+		err = v.ValidatePreRefill(ctx, &testToken{sub: "user", iat: iat})
+		if errors.Is(err, ErrTokenRevoked) != revoked {
+			t.Errorf("token issued at %d: expected revoked %v, got %v", iat, revoked, err)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package session
+
+import "errors"
+
+// ErrSessionNotFound is returned when there is no session with given ID in store.
+var ErrSessionNotFound = errors.New("rocho/session: Session not found")
+
+// ErrSessionExpired is returned when session has expired.
+var ErrSessionExpired = errors.New("rocho/session: Session has expired")
+
+// ErrMalformedSessionID is returned when session ID has invalid format.
+var ErrMalformedSessionID = errors.New("rocho/session: Malformed session ID")
@@ -0,0 +1,224 @@
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+type fileRecord struct {
+	CreatedAt  time.Time       `json:"created_at"`
+	LastSeenAt time.Time       `json:"last_seen_at"`
+	Expiry     time.Time       `json:"expiry"`
+	AuthToken  json.RawMessage `json:"auth_token"`
+}
+
+const fileStoreExt = ".session"
+
+// FileStore is Store, which keeps each session in separate file in given directory.
+// AuthTokens are stored as JSON.
+//
+// Files are named after hash of session ID, so IDs are not leaked by directory listing.
+// Expired sessions are removed when loaded or by Cleanup.
+type FileStore struct {
+	Dir string
+
+	// NewAuthToken creates value, which stored JSON is unmarshaled to.
+	// It should return pointer. If nil, JSON is unmarshaled to map[string]interface{}.
+	NewAuthToken func() rocho.AuthToken
+
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	lock sync.RWMutex
+}
+
+func (fs *FileStore) now() time.Time {
+	if fs.Now != nil {
+		return fs.Now()
+	}
+	return time.Now()
+}
+
+func (fs *FileStore) path(id string) string {
+	h := sha256.Sum256([]byte(id))
+	return filepath.Join(fs.Dir, hex.EncodeToString(h[:])+fileStoreExt)
+}
+
+func (fs *FileStore) LoadSession(ctx context.Context, id string) (s *Session, err error) {
+	fs.lock.RLock()
+	data, err := ioutil.ReadFile(fs.path(id))
+	fs.lock.RUnlock()
+	if os.IsNotExist(err) {
+		err = ErrSessionNotFound
+		return
+	} else if err != nil {
+		return
+	}
+
+	var rec fileRecord
+	err = json.Unmarshal(data, &rec)
+	if err != nil {
+		return
+	}
+
+	loaded := &Session{
+		ID:         id,
+		CreatedAt:  rec.CreatedAt,
+		LastSeenAt: rec.LastSeenAt,
+		Expiry:     rec.Expiry,
+	}
+	if loaded.expired(fs.now()) {
+		err = fs.DeleteSession(ctx, id)
+		if err == nil {
+			err = ErrSessionNotFound
+		}
+		return
+	}
+
+	if fs.NewAuthToken != nil {
+		loaded.AuthToken = fs.NewAuthToken()
+	} else {
+		loaded.AuthToken = &map[string]interface{}{}
+	}
+	err = json.Unmarshal(rec.AuthToken, loaded.AuthToken)
+	if err != nil {
+		return
+	}
+
+	s = loaded
+	return
+}
+
+func (fs *FileStore) SaveSession(ctx context.Context, s *Session) (err error) {
+	rawToken, err := json.Marshal(s.AuthToken)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(fileRecord{
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		Expiry:     s.Expiry,
+		AuthToken:  rawToken,
+	})
+	if err != nil {
+		return
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	err = fs.write(s.ID, data)
+	return
+}
+
+func (fs *FileStore) TouchSession(ctx context.Context, id string, lastSeenAt, expiry time.Time) (err error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	data, err := ioutil.ReadFile(fs.path(id))
+	if os.IsNotExist(err) {
+		err = ErrSessionNotFound
+		return
+	} else if err != nil {
+		return
+	}
+
+	var rec fileRecord
+	err = json.Unmarshal(data, &rec)
+	if err != nil {
+		return
+	}
+	if !rec.Expiry.IsZero() && !fs.now().Before(rec.Expiry) {
+		err = ErrSessionNotFound
+		return
+	}
+
+	rec.LastSeenAt = lastSeenAt
+	rec.Expiry = expiry
+	data, err = json.Marshal(rec)
+	if err != nil {
+		return
+	}
+
+	err = fs.write(id, data)
+	return
+}
+
+// write replaces file of session with given data. Lock must be held by caller.
+func (fs *FileStore) write(id string, data []byte) (err error) {
+	// write to temporary file and rename it, so readers never see partial writes
+	f, err := ioutil.TempFile(fs.Dir, ".tmp-")
+	if err != nil {
+		return
+	}
+	tmpName := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return
+	}
+
+	err = os.Rename(tmpName, fs.path(id))
+	if err != nil {
+		_ = os.Remove(tmpName)
+	}
+	return
+}
+
+func (fs *FileStore) DeleteSession(ctx context.Context, id string) (err error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	err = os.Remove(fs.path(id))
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
+
+// Cleanup removes all expired sessions from directory.
+func (fs *FileStore) Cleanup(ctx context.Context) (err error) {
+	infos, err := ioutil.ReadDir(fs.Dir)
+	if err != nil {
+		return
+	}
+
+	now := fs.now()
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), fileStoreExt) {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		path := filepath.Join(fs.Dir, info.Name())
+
+		fs.lock.Lock()
+		data, rerr := ioutil.ReadFile(path)
+		var rec fileRecord
+		if rerr == nil && json.Unmarshal(data, &rec) == nil {
+			if !rec.Expiry.IsZero() && !now.Before(rec.Expiry) {
+				_ = os.Remove(path)
+			}
+		}
+		fs.lock.Unlock()
+	}
+	return
+}
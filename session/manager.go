@@ -0,0 +1,158 @@
+// Package session implements server-side sessions.
+//
+// Client gets only random opaque session ID, while AuthToken is kept in Store, so sessions can be destroyed
+// immediately.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+const idSize = 32
+
+var encoding = base64.RawURLEncoding
+
+// Manager implements rocho.AuthTokenSerializer, rocho.AuthTokenRefiller and rocho.AuthTokenRevoker
+// using server-side sessions.
+//
+// Deserialized AuthToken is *Session, which contains AuthToken passed to SerializeAuthToken.
+// Session is loaded from Store during deserialization, so unknown, destroyed or expired session IDs are rejected
+// even if Manager is not registered as AuthTokenRefiller. Registering it is still required for IdleTimeout to work,
+// since sessions are extended only by ProcessAuthToken.
+type Manager struct {
+	Store Store
+
+	// IdleTimeout is sliding expiration. Each use of session extends it's lifetime by IdleTimeout.
+	// Zero disables it.
+	IdleTimeout time.Duration
+	// MaxLifetime is absolute lifetime of session, which can't be extended. Zero disables it.
+	MaxLifetime time.Duration
+
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+func (m *Manager) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+func (m *Manager) expiry(s *Session, now time.Time) (expiry time.Time) {
+	if m.IdleTimeout > 0 {
+		expiry = now.Add(m.IdleTimeout)
+	}
+	if m.MaxLifetime > 0 {
+		absolute := s.CreatedAt.Add(m.MaxLifetime)
+		if expiry.IsZero() || absolute.Before(expiry) {
+			expiry = absolute
+		}
+	}
+	return
+}
+
+// SerializeAuthToken creates new session with given AuthToken and returns it's ID.
+// If AuthToken is *Session, it's saved and it's ID is returned.
+func (m *Manager) SerializeAuthToken(ctx context.Context, at rocho.AuthToken) (data []byte, err error) {
+	s, ok := at.(*Session)
+	if !ok {
+		now := m.now()
+
+		rawID := make([]byte, idSize)
+		_, err = io.ReadFull(rand.Reader, rawID)
+		if err != nil {
+			return
+		}
+
+		s = &Session{
+			ID:         encoding.EncodeToString(rawID),
+			AuthToken:  at,
+			CreatedAt:  now,
+			LastSeenAt: now,
+		}
+		s.Expiry = m.expiry(s, now)
+	}
+
+	err = m.Store.SaveSession(ctx, s)
+	if err != nil {
+		return
+	}
+
+	data = []byte(s.ID)
+	return
+}
+
+// DeserializeAuthToken loads session with given ID.
+// It returns ErrSessionNotFound if there is no such session and ErrSessionExpired if it has expired.
+func (m *Manager) DeserializeAuthToken(ctx context.Context, data []byte) (at rocho.AuthToken, err error) {
+	rawID, err := encoding.DecodeString(string(data))
+	if err != nil || len(rawID) != idSize {
+		err = ErrMalformedSessionID
+		return
+	}
+
+	s, err := m.Store.LoadSession(ctx, string(data))
+	if err != nil {
+		return
+	}
+	if s.expired(m.now()) {
+		err = ErrSessionExpired
+		return
+	}
+
+	at = s
+	return
+}
+
+// ProcessAuthToken extends lifetime of *Session.
+// Returns rocho.ErrAuthDataNotSupported for other AuthTokens.
+func (m *Manager) ProcessAuthToken(ctx context.Context, at rocho.AuthToken) (rat rocho.AuthToken, err error) {
+	s, ok := at.(*Session)
+	if !ok {
+		err = rocho.ErrAuthDataNotSupported
+		return
+	}
+
+	now := m.now()
+	if s.expired(now) {
+		err = ErrSessionExpired
+		return
+	}
+
+	if m.IdleTimeout > 0 {
+		s.LastSeenAt = now
+		s.Expiry = m.expiry(s, now)
+		// never use SaveSession here, since it would recreate session destroyed in meantime
+		err = m.Store.TouchSession(ctx, s.ID, s.LastSeenAt, s.Expiry)
+		if err != nil {
+			return
+		}
+	}
+
+	rat = s
+	return
+}
+
+// RevokeAuthToken destroys session given as *Session.
+func (m *Manager) RevokeAuthToken(ctx context.Context, at rocho.AuthToken) (err error) {
+	s, ok := at.(*Session)
+	if !ok {
+		err = rocho.ErrAuthDataNotSupported
+		return
+	}
+	err = m.Destroy(ctx, s.ID)
+	return
+}
+
+// Destroy removes session with given ID.
+func (m *Manager) Destroy(ctx context.Context, id string) (err error) {
+	err = m.Store.DeleteSession(ctx, id)
+	return
+}
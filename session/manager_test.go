@@ -0,0 +1,152 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+func testStores(t *testing.T) (stores map[string]Store, cleanup func()) {
+	dir, err := ioutil.TempDir("", "rocho-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup = func() {
+		_ = os.RemoveAll(dir)
+	}
+
+	stores = map[string]Store{
+		"memory": &MemoryStore{},
+		"file":   &FileStore{Dir: dir},
+	}
+	return
+}
+
+func TestStore_TouchDoesNotRecreateSession(t *testing.T) {
+	ctx := context.Background()
+	stores, cleanup := testStores(t)
+	defer cleanup()
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			s := &Session{ID: "id", AuthToken: map[string]interface{}{}, CreatedAt: now, LastSeenAt: now}
+			err := store.SaveSession(ctx, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expiry := now.Add(time.Hour)
+			err = store.TouchSession(ctx, s.ID, now, expiry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := store.LoadSession(ctx, s.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !loaded.Expiry.Equal(expiry) {
+				t.Errorf("expected expiry %s, got %s", expiry, loaded.Expiry)
+			}
+
+			err = store.DeleteSession(ctx, s.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = store.TouchSession(ctx, s.ID, now, expiry)
+			if !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("expected ErrSessionNotFound, got %v", err)
+			}
+			_, err = store.LoadSession(ctx, s.ID)
+			if !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("expected destroyed session to stay destroyed, got %v", err)
+			}
+		})
+	}
+}
+
+// destroyingStore destroys session right after it's loaded, like concurrent logout would.
+type destroyingStore struct {
+	Store
+}
+
+func (ds *destroyingStore) LoadSession(ctx context.Context, id string) (s *Session, err error) {
+	s, err = ds.Store.LoadSession(ctx, id)
+	if err != nil {
+		return
+	}
+	err = ds.Store.DeleteSession(ctx, id)
+	return
+}
+
+func TestManager_ProcessAuthTokenAfterConcurrentDestroy(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryStore{}
+	m := &Manager{Store: &destroyingStore{Store: store}, IdleTimeout: time.Minute}
+
+	data, err := m.SerializeAuthToken(ctx, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := m.DeserializeAuthToken(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.ProcessAuthToken(ctx, s)
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+	_, err = store.LoadSession(ctx, string(data))
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected session not to be recreated, got %v", err)
+	}
+}
+
+func TestManager_RejectsUnknownSessionID(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	m := &Manager{
+		Store:       &MemoryStore{},
+		MaxLifetime: time.Minute,
+		Now: func() time.Time {
+			return now
+		},
+	}
+	// manager is not registered as refiller
+	engine := &rocho.DefaultSessionEngine{AuthTokenDeserializer: m}
+
+	forged := encoding.EncodeToString(make([]byte, idSize))
+	_, err := engine.GetRawAuthToken(ctx, []byte(forged))
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected forged ID to be rejected, got %v", err)
+	}
+
+	data, err := m.SerializeAuthToken(ctx, map[string]interface{}{"sub": "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at, err := engine.GetRawAuthToken(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at.(*Session).AuthToken.(map[string]interface{})["sub"] != "user" {
+		t.Errorf("unexpected AuthToken %+v", at)
+	}
+
+	now = now.Add(time.Minute)
+	_, err = engine.GetRawAuthToken(ctx, data)
+	if !errors.Is(err, ErrSessionNotFound) && !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("expected expired session to be rejected, got %v", err)
+	}
+
+	_, err = engine.GetRawAuthToken(ctx, []byte("short"))
+	if !errors.Is(err, ErrMalformedSessionID) {
+		t.Errorf("expected ErrMalformedSessionID, got %v", err)
+	}
+}
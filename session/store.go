@@ -0,0 +1,131 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+// Session is AuthToken stored on server side with it's metadata.
+type Session struct {
+	ID        string
+	AuthToken rocho.AuthToken
+
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	// Expiry is time after which session is no longer valid. Zero means that it never expires.
+	Expiry time.Time
+}
+
+// ExpiresAt implements rocho.ExpiringAuthToken.
+func (s *Session) ExpiresAt() time.Time {
+	return s.Expiry
+}
+
+func (s *Session) expired(now time.Time) bool {
+	return !s.Expiry.IsZero() && !now.Before(s.Expiry)
+}
+
+// Store keeps sessions.
+// Implementations should not return expired sessions.
+type Store interface {
+	// LoadSession returns ErrSessionNotFound if there is no such session.
+	LoadSession(ctx context.Context, id string) (s *Session, err error)
+	SaveSession(ctx context.Context, s *Session) (err error)
+	// TouchSession updates LastSeenAt and Expiry of existing session.
+	// Unlike SaveSession, it never recreates session. It returns ErrSessionNotFound if there is no such session,
+	// for instance because it was destroyed concurrently.
+	TouchSession(ctx context.Context, id string, lastSeenAt, expiry time.Time) (err error)
+	// DeleteSession does not return error if there is no such session.
+	DeleteSession(ctx context.Context, id string) (err error)
+}
+
+// MemoryStore is Store, which keeps sessions in memory.
+// Expired sessions are evicted periodically during saves.
+type MemoryStore struct {
+	// CleanupInterval is minimal time between evictions of expired sessions. If zero, one minute is used.
+	CleanupInterval time.Duration
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	lock        sync.Mutex
+	sessions    map[string]Session
+	lastCleanup time.Time
+}
+
+func (ms *MemoryStore) now() time.Time {
+	if ms.Now != nil {
+		return ms.Now()
+	}
+	return time.Now()
+}
+
+func (ms *MemoryStore) LoadSession(ctx context.Context, id string) (s *Session, err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	stored, ok := ms.sessions[id]
+	if !ok {
+		err = ErrSessionNotFound
+		return
+	}
+	if stored.expired(ms.now()) {
+		delete(ms.sessions, id)
+		err = ErrSessionNotFound
+		return
+	}
+
+	s = &stored
+	return
+}
+
+func (ms *MemoryStore) SaveSession(ctx context.Context, s *Session) (err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	if ms.sessions == nil {
+		ms.sessions = map[string]Session{}
+	}
+	ms.sessions[s.ID] = *s
+
+	now := ms.now()
+	interval := ms.CleanupInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if now.Sub(ms.lastCleanup) >= interval {
+		ms.lastCleanup = now
+		for id, stored := range ms.sessions {
+			if stored.expired(now) {
+				delete(ms.sessions, id)
+			}
+		}
+	}
+	return
+}
+
+func (ms *MemoryStore) TouchSession(ctx context.Context, id string, lastSeenAt, expiry time.Time) (err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	stored, ok := ms.sessions[id]
+	if !ok || stored.expired(ms.now()) {
+		err = ErrSessionNotFound
+		return
+	}
+
+	stored.LastSeenAt = lastSeenAt
+	stored.Expiry = expiry
+	ms.sessions[id] = stored
+	return
+}
+
+func (ms *MemoryStore) DeleteSession(ctx context.Context, id string) (err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	delete(ms.sessions, id)
+	return
+}
@@ -0,0 +1,43 @@
+package rocho
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrTooManyAttempts is returned when authentication attempt was rejected by AuthThrottler.
+// Actual error is *TooManyAttemptsError, which contains time after which attempt may be retried.
+var ErrTooManyAttempts = errors.New("rocho: Too many authentication attempts")
+
+// TooManyAttemptsError is returned when authentication attempt was rejected by AuthThrottler.
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (err *TooManyAttemptsError) Error() string {
+	if err == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("rocho: Too many authentication attempts, retry after %s", err.RetryAfter)
+}
+
+// Is makes errors.Is(err, ErrTooManyAttempts) work.
+func (err *TooManyAttemptsError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// AuthThrottler limits rate of authentication attempts.
+// Request passed to it may be nil, when AuthData was not obtained from request.
+type AuthThrottler interface {
+	// CheckAttempt returns *TooManyAttemptsError if attempt should not be performed.
+	// Otherwise it reserves attempt, so concurrent attempts are throttled as well.
+	CheckAttempt(ctx context.Context, r *http.Request, ad AuthData) (err error)
+	// RecordAttempt records result of attempt, which was allowed by CheckAttempt.
+	// Result is error returned by authentication or nil if it succeeded.
+	// Only errors matching ErrInvalidCredentials should be counted as failed attempts,
+	// so failures of backends do not lock users out.
+	RecordAttempt(ctx context.Context, r *http.Request, ad AuthData, result error) (err error)
+}
@@ -0,0 +1,144 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Counter counts failed attempts for single key.
+// It also counts attempts in progress, which were reserved, but their result is not known yet.
+type Counter struct {
+	Count       int
+	LastAttempt time.Time
+	ExpiresAt   time.Time
+}
+
+func (c *Counter) expired(now time.Time) bool {
+	return !c.ExpiresAt.IsZero() && !now.Before(c.ExpiresAt)
+}
+
+// CounterStore keeps counters of failed attempts.
+// Implementations should not return expired counters.
+type CounterStore interface {
+	// GetCounter returns zero Counter if there is no counter for given key.
+	GetCounter(ctx context.Context, key string) (c Counter, err error)
+	// ReserveAttempt atomically checks whether attempt for given key is allowed by limit and counts it if so.
+	// If it's not allowed, it's not counted and positive time, which has to pass before it's allowed, is returned.
+	//
+	// Counted attempt sets expiration of counter to limit's Window from now.
+	// Counter does not expire if Window is not positive.
+	ReserveAttempt(ctx context.Context, key string, limit *Limit) (retryAfter time.Duration, err error)
+	// ReleaseAttempt uncounts attempt reserved with ReserveAttempt, which turned out not to be failed one.
+	// It does not return error if there is no counter for given key.
+	ReleaseAttempt(ctx context.Context, key string) (err error)
+	// ResetCounter does not return error if there is no counter for given key.
+	ResetCounter(ctx context.Context, key string) (err error)
+}
+
+// MemoryStore is CounterStore, which keeps counters in memory.
+// Expired counters are evicted periodically during reservations.
+type MemoryStore struct {
+	// CleanupInterval is minimal time between evictions of expired counters. If zero, one minute is used.
+	CleanupInterval time.Duration
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	lock        sync.Mutex
+	counters    map[string]Counter
+	lastCleanup time.Time
+}
+
+func (ms *MemoryStore) now() time.Time {
+	if ms.Now != nil {
+		return ms.Now()
+	}
+	return time.Now()
+}
+
+func (ms *MemoryStore) GetCounter(ctx context.Context, key string) (c Counter, err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	stored, ok := ms.counters[key]
+	if !ok {
+		return
+	}
+	if stored.expired(ms.now()) {
+		delete(ms.counters, key)
+		return
+	}
+
+	c = stored
+	return
+}
+
+func (ms *MemoryStore) ReserveAttempt(ctx context.Context, key string, limit *Limit) (retryAfter time.Duration, err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	if ms.counters == nil {
+		ms.counters = map[string]Counter{}
+	}
+
+	now := ms.now()
+	c := ms.counters[key]
+	if c.expired(now) {
+		c = Counter{}
+	}
+
+	if c.Count > 0 {
+		wait := c.LastAttempt.Add(limit.Delay(c.Count)).Sub(now)
+		if wait > 0 {
+			retryAfter = wait
+			return
+		}
+	}
+
+	c.Count++
+	c.LastAttempt = now
+	c.ExpiresAt = time.Time{}
+	if limit.Window > 0 {
+		c.ExpiresAt = now.Add(limit.Window)
+	}
+	ms.counters[key] = c
+
+	interval := ms.CleanupInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if now.Sub(ms.lastCleanup) >= interval {
+		ms.lastCleanup = now
+		for key, stored := range ms.counters {
+			if stored.expired(now) {
+				delete(ms.counters, key)
+			}
+		}
+	}
+	return
+}
+
+func (ms *MemoryStore) ReleaseAttempt(ctx context.Context, key string) (err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	c, ok := ms.counters[key]
+	if !ok {
+		return
+	}
+	c.Count--
+	if c.Count <= 0 {
+		delete(ms.counters, key)
+		return
+	}
+	ms.counters[key] = c
+	return
+}
+
+func (ms *MemoryStore) ResetCounter(ctx context.Context, key string) (err error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	delete(ms.counters, key)
+	return
+}
@@ -0,0 +1,219 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+// Limit configures throttling of single kind of key.
+//
+// First FreeAttempts attempts are not delayed, so with FreeAttempts of 5 the 6th one has to wait BaseDelay
+// since last failed attempt. First attempt has nothing to wait for, so FreeAttempts of zero works like one.
+// Each next failed attempt doubles delay, capped at MaxDelay.
+// If MaxDelay is zero, delay stops growing once doubling it would overflow.
+// After LockoutThreshold failed attempts key is locked out for LockoutDuration.
+// Failed attempts are forgotten after Window passes since last one.
+type Limit struct {
+	// Disabled turns off throttling of this kind of key.
+	Disabled bool
+
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+
+	// LockoutThreshold of zero disables lockouts.
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+
+	// Window of zero makes failed attempts never forgotten.
+	Window time.Duration
+}
+
+// Delay returns time, which has to pass since last failed attempt, when given amount of failed attempts was made.
+func (l *Limit) Delay(failures int) (d time.Duration) {
+	if l.Disabled {
+		return
+	}
+	if l.LockoutThreshold > 0 && failures >= l.LockoutThreshold {
+		d = l.LockoutDuration
+		return
+	}
+	free := l.FreeAttempts
+	if free < 1 {
+		free = 1
+	}
+	if failures < free {
+		return
+	}
+
+	d = l.BaseDelay
+	for i := free; i < failures; i++ {
+		if (l.MaxDelay > 0 && d >= l.MaxDelay) || d > math.MaxInt64/2 {
+			break
+		}
+		d *= 2
+	}
+	if l.MaxDelay > 0 && d > l.MaxDelay {
+		d = l.MaxDelay
+	}
+	return
+}
+
+// DefaultUsernameLimit is used for usernames, when Throttler.UsernameLimit is nil.
+var DefaultUsernameLimit = Limit{
+	FreeAttempts:     5,
+	BaseDelay:        time.Second,
+	MaxDelay:         time.Minute,
+	LockoutThreshold: 20,
+	LockoutDuration:  15 * time.Minute,
+	Window:           time.Hour,
+}
+
+// DefaultIPLimit is used for IP addresses, when Throttler.IPLimit is nil.
+// It's more lenient than DefaultUsernameLimit, since many users may share single address.
+var DefaultIPLimit = Limit{
+	FreeAttempts:     20,
+	BaseDelay:        time.Second,
+	MaxDelay:         time.Minute,
+	LockoutThreshold: 100,
+	LockoutDuration:  15 * time.Minute,
+	Window:           time.Hour,
+}
+
+// DefaultPairLimit is used for (username, IP address) pairs, when Throttler.PairLimit is nil.
+var DefaultPairLimit = Limit{
+	FreeAttempts:     3,
+	BaseDelay:        time.Second,
+	MaxDelay:         5 * time.Minute,
+	LockoutThreshold: 10,
+	LockoutDuration:  30 * time.Minute,
+	Window:           time.Hour,
+}
+
+type usernameAuthData interface {
+	GetUsername() string
+}
+
+// Throttler is rocho.AuthThrottler, which counts failed attempts per username, IP address and (username, IP address) pair.
+// Attempt is rejected if any of these keys is throttled.
+// Successful attempt resets counter of it's pair.
+//
+// Only attempts failed with rocho.ErrInvalidCredentials are counted.
+// Attempts in progress are counted as failed ones, so concurrent attempts can't bypass delays.
+//
+// Username is obtained from AuthData implementing GetUsername, like rocho.ClassicAuthData.
+type Throttler struct {
+	// Store keeps counters. It's required.
+	Store CounterStore
+
+	// Limits for each kind of key. If nil, default ones are used.
+	UsernameLimit *Limit
+	IPLimit       *Limit
+	PairLimit     *Limit
+
+	// ClientIP returns IP address of client. If nil, host part of request's RemoteAddr is used.
+	ClientIP func(r *http.Request) string
+}
+
+type throttledKey struct {
+	key   string
+	limit *Limit
+	pair  bool
+}
+
+func (t *Throttler) clientIP(r *http.Request) string {
+	if t.ClientIP != nil {
+		return t.ClientIP(r)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (t *Throttler) keys(r *http.Request, ad rocho.AuthData) (keys []throttledKey) {
+	var username, ip string
+	if uad, ok := ad.(usernameAuthData); ok {
+		username = strings.ToLower(uad.GetUsername())
+	}
+	if r != nil {
+		ip = t.clientIP(r)
+	}
+
+	pick := func(l *Limit, def *Limit) *Limit {
+		if l != nil {
+			return l
+		}
+		return def
+	}
+	if username != "" {
+		keys = append(keys, throttledKey{key: "u:" + username, limit: pick(t.UsernameLimit, &DefaultUsernameLimit)})
+	}
+	if ip != "" {
+		keys = append(keys, throttledKey{key: "i:" + ip, limit: pick(t.IPLimit, &DefaultIPLimit)})
+	}
+	if username != "" && ip != "" {
+		keys = append(keys, throttledKey{key: "p:" + ip + ":" + username, limit: pick(t.PairLimit, &DefaultPairLimit), pair: true})
+	}
+	return
+}
+
+// CheckAttempt returns *rocho.TooManyAttemptsError if any of keys of attempt is throttled.
+// Otherwise it reserves attempt for all keys, so attempt is counted as failed one until RecordAttempt says otherwise.
+func (t *Throttler) CheckAttempt(ctx context.Context, r *http.Request, ad rocho.AuthData) (err error) {
+	var reserved []throttledKey
+	for _, k := range t.keys(r, ad) {
+		if k.limit.Disabled {
+			continue
+		}
+
+		var retryAfter time.Duration
+		retryAfter, err = t.Store.ReserveAttempt(ctx, k.key, k.limit)
+		if err == nil && retryAfter > 0 {
+			err = &rocho.TooManyAttemptsError{
+				RetryAfter: retryAfter,
+			}
+		}
+		if err != nil {
+			for _, rk := range reserved {
+				_ = t.Store.ReleaseAttempt(ctx, rk.key)
+			}
+			return
+		}
+		reserved = append(reserved, k)
+	}
+	return
+}
+
+// RecordAttempt keeps attempt reserved by CheckAttempt counted only if it failed with rocho.ErrInvalidCredentials.
+// Successful attempt also resets counter of (username, IP address) pair,
+// but not others, so successful login does not lift throttling of username attacked from other addresses.
+func (t *Throttler) RecordAttempt(ctx context.Context, r *http.Request, ad rocho.AuthData, result error) (err error) {
+	if errors.Is(result, rocho.ErrInvalidCredentials) {
+		return
+	}
+
+	for _, k := range t.keys(r, ad) {
+		if k.limit.Disabled {
+			continue
+		}
+
+		if result == nil && k.pair {
+			err = t.Store.ResetCounter(ctx, k.key)
+		} else {
+			err = t.Store.ReleaseAttempt(ctx, k.key)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,199 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+func TestLimit_DelayDoesNotOverflow(t *testing.T) {
+	l := Limit{BaseDelay: time.Second}
+
+	prev := l.Delay(1)
+	for failures := 2; failures <= 1000; failures++ {
+		d := l.Delay(failures)
+		if d < prev {
+			t.Fatalf("delay decreased from %s to %s at %d failures", prev, d, failures)
+		}
+		prev = d
+	}
+}
+
+func TestLimit_DelayCapped(t *testing.T) {
+	l := Limit{FreeAttempts: 2, BaseDelay: time.Second, MaxDelay: 5 * time.Second, LockoutThreshold: 10, LockoutDuration: time.Hour}
+
+	for failures, expected := range map[int]time.Duration{
+		1:  0,
+		2:  time.Second,
+		3:  2 * time.Second,
+		4:  4 * time.Second,
+		5:  5 * time.Second,
+		9:  5 * time.Second,
+		10: time.Hour,
+	} {
+		if d := l.Delay(failures); d != expected {
+			t.Errorf("expected %s for %d failures, got %s", expected, failures, d)
+		}
+	}
+}
+
+func TestLimit_DelayFreeAttempts(t *testing.T) {
+	for name, tc := range map[string]struct {
+		free     int
+		expected map[int]time.Duration
+	}{
+		"five": {free: 5, expected: map[int]time.Duration{0: 0, 4: 0, 5: time.Second, 6: 2 * time.Second}},
+		"one":  {free: 1, expected: map[int]time.Duration{0: 0, 1: time.Second, 2: 2 * time.Second}},
+		"zero": {free: 0, expected: map[int]time.Duration{0: 0, 1: time.Second, 2: 2 * time.Second}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			l := Limit{FreeAttempts: tc.free, BaseDelay: time.Second}
+			for failures, expected := range tc.expected {
+				if d := l.Delay(failures); d != expected {
+					t.Errorf("expected %s for %d failures, got %s", expected, failures, d)
+				}
+			}
+		})
+	}
+}
+
+func TestThrottler_FreeAttempts(t *testing.T) {
+	ctx := context.Background()
+	th := &Throttler{
+		Store:         &MemoryStore{},
+		UsernameLimit: &Limit{FreeAttempts: 5, BaseDelay: time.Minute},
+		IPLimit:       &Limit{Disabled: true},
+		PairLimit:     &Limit{Disabled: true},
+	}
+	ad := rocho.ClassicAuthData{Username: "bob"}
+
+	for i := 1; i <= 5; i++ {
+		err := th.CheckAttempt(ctx, nil, ad)
+		if err != nil {
+			t.Fatalf("expected attempt %d to be free, got %v", i, err)
+		}
+		err = th.RecordAttempt(ctx, nil, ad, rocho.ErrInvalidCredentials)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := th.CheckAttempt(ctx, nil, ad)
+	if !errors.Is(err, rocho.ErrTooManyAttempts) {
+		t.Errorf("expected 6th attempt to be delayed, got %v", err)
+	}
+}
+
+func newTestThrottler() *Throttler {
+	limit := &Limit{FreeAttempts: 2, BaseDelay: time.Minute, Window: time.Hour}
+	return &Throttler{
+		Store:         &MemoryStore{},
+		UsernameLimit: limit,
+		IPLimit:       &Limit{Disabled: true},
+		PairLimit:     &Limit{Disabled: true},
+	}
+}
+
+func TestThrottler_ConcurrentAttemptsAreReserved(t *testing.T) {
+	th := newTestThrottler()
+	ad := rocho.ClassicAuthData{Username: "bob"}
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if th.CheckAttempt(context.Background(), nil, ad) == nil {
+				lock.Lock()
+				allowed++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// attempts in progress count as failures, so only free attempts pass
+	if allowed != 2 {
+		t.Errorf("expected 2 attempts to be allowed, got %d", allowed)
+	}
+}
+
+func TestThrottler_OnlyInvalidCredentialsAreCounted(t *testing.T) {
+	ctx := context.Background()
+	ad := rocho.ClassicAuthData{Username: "bob"}
+
+	for _, tc := range []struct {
+		name      string
+		result    error
+		throttled bool
+	}{
+		{name: "invalid credentials", result: rocho.ErrInvalidCredentials, throttled: true},
+		{name: "provider failure", result: &rocho.ProviderFiledError{Err: errors.New("down")}, throttled: false},
+		{name: "no user data", result: rocho.ErrNoUserData, throttled: false},
+		{name: "success", result: nil, throttled: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			th := newTestThrottler()
+			for i := 0; i < 10; i++ {
+				err := th.CheckAttempt(ctx, nil, ad)
+				if err != nil {
+					if !tc.throttled {
+						t.Fatalf("unexpected error on attempt %d: %v", i, err)
+					}
+					if !errors.Is(err, rocho.ErrTooManyAttempts) {
+						t.Fatalf("expected ErrTooManyAttempts, got %v", err)
+					}
+					return
+				}
+				err = th.RecordAttempt(ctx, nil, ad, tc.result)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.throttled {
+				t.Error("expected attempts to be throttled")
+			}
+		})
+	}
+}
+
+func TestThrottler_RejectedAttemptReleasesOtherKeys(t *testing.T) {
+	ctx := context.Background()
+	th := &Throttler{
+		Store:         &MemoryStore{},
+		UsernameLimit: &Limit{BaseDelay: time.Minute},
+		IPLimit:       &Limit{FreeAttempts: 100},
+		PairLimit:     &Limit{Disabled: true},
+	}
+	r := httptest.NewRequest("POST", "/", nil)
+	ad := rocho.ClassicAuthData{Username: "bob"}
+
+	// first attempt is free, second is delayed by username
+	err := th.CheckAttempt(ctx, r, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = th.RecordAttempt(ctx, r, ad, rocho.ErrInvalidCredentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = th.CheckAttempt(ctx, r, ad)
+	if !errors.Is(err, rocho.ErrTooManyAttempts) {
+		t.Fatalf("expected ErrTooManyAttempts, got %v", err)
+	}
+
+	c, err := th.Store.GetCounter(ctx, "i:"+th.clientIP(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Count != 1 {
+		t.Errorf("expected rejected attempt not to be counted for IP, got count %d", c.Count)
+	}
+}
@@ -0,0 +1,164 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+const bearerScheme = "Bearer"
+
+// BearerResponse is JSON body written by Bearer.SerializeAuthTokenToResponse.
+// It follows RFC 6749 section 5.1.
+type BearerResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in,omitempty"`
+}
+
+// Bearer implements rocho.HTTPAuthTokenSerializer using bearer tokens as described in RFC 6750.
+// It wraps any rocho.AuthTokenSerializer, which must produce tokens consisting of URL-safe characters only,
+// which is the case for all serializers in rocho.
+//
+// Token is read from "Authorization: Bearer <token>" header.
+// Optionally it can be read from query parameter or form-encoded body field as well.
+//
+// Token is written to response as JSON body, so API clients can store it.
+type Bearer struct {
+	Serializer rocho.AuthTokenSerializer
+
+	// QueryParameter, if not empty, is name of URL query parameter token is read from if there is no header.
+	QueryParameter string
+	// FormField, if not empty, is name of form-encoded body field token is read from if there is no header.
+	// It's read only from requests with "application/x-www-form-urlencoded" content type.
+	// Note: reading it consumes request body, so handlers must use r.PostForm instead of reading body again.
+	FormField string
+
+	// Realm is used in WWW-Authenticate header.
+	Realm string
+
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+func (b *Bearer) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+	return time.Now()
+}
+
+// SerializeAuthTokenToResponse serializes AuthToken and writes it as JSON body.
+func (b *Bearer) SerializeAuthTokenToResponse(ctx context.Context, at rocho.AuthToken, w http.ResponseWriter) (err error) {
+	data, err := b.Serializer.SerializeAuthToken(ctx, at)
+	if err != nil {
+		return
+	}
+
+	res := BearerResponse{
+		AccessToken: string(data),
+		TokenType:   bearerScheme,
+	}
+	if eat, ok := at.(rocho.ExpiringAuthToken); ok {
+		expiresAt := eat.ExpiresAt()
+		if !expiresAt.IsZero() {
+			res.ExpiresIn = int64(expiresAt.Sub(b.now()) / time.Second)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(res)
+	return
+}
+
+// DeserializeAuthTokenFromRequest reads bearer token from request and deserializes AuthToken from it.
+// Returns rocho.ErrNoAuthToken if there is no token and ErrMultipleTokens if more than one method was used.
+// If FormField is set, body of form-encoded requests is parsed, which consumes it.
+func (b *Bearer) DeserializeAuthTokenFromRequest(ctx context.Context, r *http.Request) (at rocho.AuthToken, err error) {
+	token, err := b.readToken(r)
+	if err != nil {
+		return
+	}
+
+	at, err = b.Serializer.DeserializeAuthToken(ctx, []byte(token))
+	return
+}
+
+func (b *Bearer) readToken(r *http.Request) (token string, err error) {
+	found := 0
+
+	if header := r.Header.Get("Authorization"); header != "" {
+		// scheme is case-insensitive
+		if len(header) > len(bearerScheme) && strings.EqualFold(header[:len(bearerScheme)], bearerScheme) && header[len(bearerScheme)] == ' ' {
+			token = strings.TrimSpace(header[len(bearerScheme)+1:])
+			found++
+		}
+	}
+
+	if b.QueryParameter != "" {
+		if v := r.URL.Query().Get(b.QueryParameter); v != "" {
+			token = v
+			found++
+		}
+	}
+
+	if b.FormField != "" && r.Method != http.MethodGet && r.Body != nil {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/x-www-form-urlencoded" {
+			if v := r.PostFormValue(b.FormField); v != "" {
+				token = v
+				found++
+			}
+		}
+	}
+
+	if found == 0 || token == "" {
+		token = ""
+		err = rocho.ErrNoAuthToken
+	} else if found > 1 {
+		token = ""
+		err = ErrMultipleTokens
+	}
+	return
+}
+
+// Challenge implements rocho.AuthChallenger. It sets WWW-Authenticate header for given error returned from
+// DeserializeAuthTokenFromRequest or later validation of token.
+//
+// rocho.ErrNoAuthToken results in plain challenge, ErrMultipleTokens in "invalid_request"
+// and all other errors in "invalid_token". Error details are never exposed.
+func (b *Bearer) Challenge(h http.Header, err error) {
+	params := []string{}
+	if b.Realm != "" {
+		params = append(params, "realm="+quoteParam(b.Realm))
+	}
+
+	switch {
+	case err == nil || errors.Is(err, rocho.ErrNoAuthToken):
+	case errors.Is(err, ErrMultipleTokens):
+		params = append(params, `error="invalid_request"`)
+	default:
+		params = append(params, `error="invalid_token"`, `error_description="The access token is invalid"`)
+	}
+
+	challenge := bearerScheme
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+	h.Set("WWW-Authenticate", challenge)
+}
+
+func quoteParam(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return `"` + v + `"`
+}
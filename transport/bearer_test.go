@@ -0,0 +1,155 @@
+package transport
+
+import (
+	"crypto"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/teawithsand/rocho"
+	"github.com/teawithsand/rocho/jwt"
+)
+
+func newTestBearer() *Bearer {
+	return &Bearer{
+		Serializer: &jwt.Serializer{
+			Method: &jwt.HMAC{Hash: crypto.SHA256, Key: []byte("0123456789abcdef0123456789abcdef")},
+		},
+		Realm: "api",
+	}
+}
+
+func serveRequired(t *testing.T, loader rocho.HTTPAuthTokenSerializer, authorization string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	return serveRequiredRequest(t, loader, r)
+}
+
+func serveRequiredRequest(t *testing.T, loader rocho.HTTPAuthTokenSerializer, r *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	engine := &rocho.DefaultSessionEngine{HTTPAuthTokenDeserializer: loader}
+	if c, ok := loader.(*Composite); ok {
+		engine.AuthTokenLoader = c
+	}
+	m := &rocho.SessionMiddleware{SessionEngine: engine, Required: true}
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected request to be rejected")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestBearer_ChallengeInUnauthorizedResponse(t *testing.T) {
+	for name, loader := range map[string]rocho.HTTPAuthTokenSerializer{
+		"bearer":    newTestBearer(),
+		"composite": &Composite{Sources: []Source{{HTTPAuthTokenSerializer: newTestBearer(), Name: "bearer"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := serveRequired(t, loader, "")
+			if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Bearer realm="api"` {
+				t.Errorf("unexpected response %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+			}
+
+			w = serveRequired(t, loader, "Bearer invalid")
+			expected := `Bearer realm="api", error="invalid_token", error_description="The access token is invalid"`
+			if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != expected {
+				t.Errorf("unexpected response %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+			}
+			if w.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("expected problem body, got %q", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestBearer_ChallengeMultipleTokens(t *testing.T) {
+	h := http.Header{}
+	newTestBearer().Challenge(h, &rocho.InvalidAuthTokenError{Err: ErrMultipleTokens})
+	if h.Get("WWW-Authenticate") != `Bearer realm="api", error="invalid_request"` {
+		t.Errorf("unexpected challenge %q", h.Get("WWW-Authenticate"))
+	}
+}
+
+func TestBearer_MultipleTokensResponse(t *testing.T) {
+	b := newTestBearer()
+	b.QueryParameter = "access_token"
+
+	r := httptest.NewRequest("GET", "/?access_token=token", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	w := serveRequiredRequest(t, b, r)
+	if w.Code != http.StatusBadRequest || w.Header().Get("WWW-Authenticate") != `Bearer realm="api", error="invalid_request"` {
+		t.Errorf("unexpected response %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+	if !strings.Contains(w.Body.String(), `"invalid_request"`) {
+		t.Errorf("expected invalid_request problem, got %s", w.Body.String())
+	}
+}
+
+func TestBearer_ReadToken(t *testing.T) {
+	b := newTestBearer()
+	b.QueryParameter = "access_token"
+	b.FormField = "access_token"
+
+	for name, tc := range map[string]struct {
+		method, url   string
+		authorization string
+		contentType   string
+		body          string
+		token         string
+		err           error
+		bodyLeft      string
+	}{
+		"header":                {authorization: "Bearer token", token: "token"},
+		"lowercase scheme":      {authorization: "bearer token", token: "token"},
+		"other scheme":          {authorization: "Basic dXNlcjpwYXNz", err: rocho.ErrNoAuthToken},
+		"empty header token":    {authorization: "Bearer ", err: rocho.ErrNoAuthToken},
+		"query":                 {url: "/?access_token=token", token: "token"},
+		"form":                  {method: "POST", contentType: "application/x-www-form-urlencoded", body: "access_token=token", token: "token"},
+		"form with charset":     {method: "POST", contentType: "application/x-www-form-urlencoded; charset=utf-8", body: "access_token=token", token: "token"},
+		"json body":             {method: "POST", contentType: "application/json", body: `{"access_token":"token"}`, err: rocho.ErrNoAuthToken, bodyLeft: `{"access_token":"token"}`},
+		"body without type":     {method: "POST", body: "access_token=token", err: rocho.ErrNoAuthToken, bodyLeft: "access_token=token"},
+		"form in GET":           {contentType: "application/x-www-form-urlencoded", body: "access_token=token", err: rocho.ErrNoAuthToken, bodyLeft: "access_token=token"},
+		"none":                  {err: rocho.ErrNoAuthToken},
+		"header and query":      {url: "/?access_token=token", authorization: "Bearer token", err: ErrMultipleTokens},
+		"header and form":       {method: "POST", authorization: "Bearer token", contentType: "application/x-www-form-urlencoded", body: "access_token=token", err: ErrMultipleTokens},
+		"query and form":        {method: "POST", url: "/?access_token=token", contentType: "application/x-www-form-urlencoded", body: "access_token=token", err: ErrMultipleTokens},
+		"header and empty form": {method: "POST", authorization: "Bearer token", contentType: "application/x-www-form-urlencoded", body: "access_token=", token: "token"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			method, url := tc.method, tc.url
+			if method == "" {
+				method = "GET"
+			}
+			if url == "" {
+				url = "/"
+			}
+			r := httptest.NewRequest(method, url, strings.NewReader(tc.body))
+			if tc.authorization != "" {
+				r.Header.Set("Authorization", tc.authorization)
+			}
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+
+			token, err := b.readToken(r)
+			if token != tc.token || !errors.Is(err, tc.err) {
+				t.Errorf("expected %q and %v, got %q and %v", tc.token, tc.err, token, err)
+			}
+
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != tc.bodyLeft {
+				t.Errorf("expected body %q to be left, got %q", tc.bodyLeft, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/teawithsand/rocho"
+)
+
+// Source is tuple of HTTPAuthTokenSerializer and Name.
+type Source struct {
+	rocho.HTTPAuthTokenSerializer
+	Name string
+}
+
+// Composite implements rocho.HTTPAuthTokenSerializer and rocho.AuthTokenLoader using many sources.
+// For instance it allows accepting both session cookies and bearer tokens on same endpoints.
+//
+// Sources are tried in order. Source returning rocho.ErrNoAuthToken is skipped.
+// First source, which contains token, wins, even if it's token is invalid.
+// In that case error is wrapped in SourceError.
+//
+// AuthTokens are serialized to response using first source only.
+type Composite struct {
+	Sources []Source
+}
+
+// LoadAuthToken loads AuthToken from first source, which contains token, and returns name of that source.
+// Returns rocho.ErrNoAuthToken if no source contains token.
+func (c *Composite) LoadAuthToken(ctx context.Context, r *http.Request) (at rocho.AuthToken, source string, err error) {
+	for _, s := range c.Sources {
+		at, err = s.DeserializeAuthTokenFromRequest(ctx, r)
+		if errors.Is(err, rocho.ErrNoAuthToken) {
+			continue
+		}
+
+		source = s.Name
+		if err != nil {
+			at = nil
+			err = &SourceError{Source: s.Name, Err: err}
+		}
+		return
+	}
+
+	err = rocho.ErrNoAuthToken
+	return
+}
+
+// LoadToken implements rocho.AuthTokenLoader.
+func (c *Composite) LoadToken(ctx context.Context, r *http.Request) (at rocho.AuthToken, err error) {
+	at, _, err = c.LoadAuthToken(ctx, r)
+	return
+}
+
+// Challenge implements rocho.AuthChallenger using sources, which implement it.
+// If token from some source was invalid, only that source sets challenge.
+func (c *Composite) Challenge(h http.Header, err error) {
+	var serr *SourceError
+	failed := errors.As(err, &serr)
+	for _, s := range c.Sources {
+		if failed && s.Name != serr.Source {
+			continue
+		}
+		if ac, ok := s.HTTPAuthTokenSerializer.(rocho.AuthChallenger); ok {
+			ac.Challenge(h, err)
+		}
+	}
+}
+
+// DeserializeAuthTokenFromRequest is same as LoadToken.
+func (c *Composite) DeserializeAuthTokenFromRequest(ctx context.Context, r *http.Request) (at rocho.AuthToken, err error) {
+	at, _, err = c.LoadAuthToken(ctx, r)
+	return
+}
+
+// SerializeAuthTokenToResponse serializes AuthToken using first source.
+func (c *Composite) SerializeAuthTokenToResponse(ctx context.Context, at rocho.AuthToken, w http.ResponseWriter) (err error) {
+	if len(c.Sources) == 0 {
+		err = ErrNoSources
+		return
+	}
+	err = c.Sources[0].SerializeAuthTokenToResponse(ctx, at, w)
+	return
+}
+
+// ClearAuthToken clears token from all sources, which implement rocho.HTTPAuthTokenClearer.
+func (c *Composite) ClearAuthToken(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+	for _, s := range c.Sources {
+		clearer, ok := s.HTTPAuthTokenSerializer.(rocho.HTTPAuthTokenClearer)
+		if !ok {
+			continue
+		}
+		err = clearer.ClearAuthToken(ctx, w, r)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
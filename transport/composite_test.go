@@ -0,0 +1,220 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/teawithsand/rocho"
+)
+
+// stubSource returns configured token or error and records calls.
+type stubSource struct {
+	name  string
+	token rocho.AuthToken
+	err   error
+	calls *[]string
+}
+
+func (s *stubSource) SerializeAuthTokenToResponse(ctx context.Context, at rocho.AuthToken, w http.ResponseWriter) (err error) {
+	*s.calls = append(*s.calls, "serialize:"+s.name)
+	return
+}
+
+func (s *stubSource) DeserializeAuthTokenFromRequest(ctx context.Context, r *http.Request) (at rocho.AuthToken, err error) {
+	*s.calls = append(*s.calls, "deserialize:"+s.name)
+	at, err = s.token, s.err
+	if at == nil && err == nil {
+		err = rocho.ErrNoAuthToken
+	}
+	return
+}
+
+// challengingSource is stubSource implementing rocho.AuthChallenger and rocho.HTTPAuthTokenClearer.
+type challengingSource struct {
+	*stubSource
+	clearErr error
+}
+
+func (s *challengingSource) Challenge(h http.Header, err error) {
+	h.Add("WWW-Authenticate", s.name)
+}
+
+func (s *challengingSource) ClearAuthToken(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+	*s.calls = append(*s.calls, "clear:"+s.name)
+	err = s.clearErr
+	return
+}
+
+func TestComposite_LoadAuthToken(t *testing.T) {
+	invalid := errors.New("invalid token")
+	for name, tc := range map[string]struct {
+		sources []stubSource
+		token   rocho.AuthToken
+		source  string
+		err     error
+		calls   []string
+	}{
+		"first wins": {
+			sources: []stubSource{{name: "a", token: "token a"}, {name: "b", token: "token b"}},
+			token:   "token a",
+			source:  "a",
+			calls:   []string{"deserialize:a"},
+		},
+		"no token falls through": {
+			sources: []stubSource{{name: "a"}, {name: "b", token: "token b"}},
+			token:   "token b",
+			source:  "b",
+			calls:   []string{"deserialize:a", "deserialize:b"},
+		},
+		"invalid token does not fall through": {
+			sources: []stubSource{{name: "a", err: invalid}, {name: "b", token: "token b"}},
+			source:  "a",
+			err:     invalid,
+			calls:   []string{"deserialize:a"},
+		},
+		"invalid token after no token": {
+			sources: []stubSource{{name: "a"}, {name: "b", err: invalid}},
+			source:  "b",
+			err:     invalid,
+			calls:   []string{"deserialize:a", "deserialize:b"},
+		},
+		"no token": {
+			sources: []stubSource{{name: "a"}, {name: "b"}},
+			err:     rocho.ErrNoAuthToken,
+			calls:   []string{"deserialize:a", "deserialize:b"},
+		},
+		"no sources": {
+			err: rocho.ErrNoAuthToken,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var calls []string
+			c := &Composite{}
+			for i := range tc.sources {
+				s := tc.sources[i]
+				s.calls = &calls
+				c.Sources = append(c.Sources, Source{HTTPAuthTokenSerializer: &s, Name: s.name})
+			}
+
+			at, source, err := c.LoadAuthToken(context.Background(), httptest.NewRequest("GET", "/", nil))
+			if at != tc.token || source != tc.source || !errors.Is(err, tc.err) {
+				t.Errorf("expected %v from %q with %v, got %v from %q with %v", tc.token, tc.source, tc.err, at, source, err)
+			}
+			if !reflect.DeepEqual(calls, tc.calls) {
+				t.Errorf("expected calls %v, got %v", tc.calls, calls)
+			}
+
+			var serr *SourceError
+			isSourceError := errors.As(err, &serr)
+			if isSourceError != (tc.err != nil && tc.err != rocho.ErrNoAuthToken) {
+				t.Errorf("expected SourceError only for invalid token, got %v", err)
+			}
+			if isSourceError && serr.Source != tc.source {
+				t.Errorf("expected source %q in SourceError, got %q", tc.source, serr.Source)
+			}
+		})
+	}
+}
+
+func TestComposite_Challenge(t *testing.T) {
+	var calls []string
+	c := &Composite{Sources: []Source{
+		{HTTPAuthTokenSerializer: &challengingSource{stubSource: &stubSource{name: "a", calls: &calls}}, Name: "a"},
+		{HTTPAuthTokenSerializer: &stubSource{name: "b", calls: &calls}, Name: "b"},
+		{HTTPAuthTokenSerializer: &challengingSource{stubSource: &stubSource{name: "c", calls: &calls}}, Name: "c"},
+	}}
+
+	for name, tc := range map[string]struct {
+		err        error
+		challenges []string
+	}{
+		"no token":          {err: rocho.ErrNoAuthToken, challenges: []string{"a", "c"}},
+		"invalid token":     {err: &rocho.InvalidAuthTokenError{Err: &SourceError{Source: "c", Err: errors.New("invalid")}}, challenges: []string{"c"}},
+		"no challenger":     {err: &SourceError{Source: "b"}},
+		"other than source": {err: errors.New("other"), challenges: []string{"a", "c"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			h := http.Header{}
+			c.Challenge(h, tc.err)
+			if !reflect.DeepEqual(h["Www-Authenticate"], tc.challenges) {
+				t.Errorf("expected challenges %v, got %v", tc.challenges, h["Www-Authenticate"])
+			}
+		})
+	}
+}
+
+func TestComposite_Serialize(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	c := &Composite{Sources: []Source{
+		{HTTPAuthTokenSerializer: &stubSource{name: "a", calls: &calls}, Name: "a"},
+		{HTTPAuthTokenSerializer: &stubSource{name: "b", calls: &calls}, Name: "b"},
+	}}
+
+	err := c.SerializeAuthTokenToResponse(ctx, "token", httptest.NewRecorder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(calls, []string{"serialize:a"}) {
+		t.Errorf("expected only first source to serialize token, got %v", calls)
+	}
+
+	err = (&Composite{}).SerializeAuthTokenToResponse(ctx, "token", httptest.NewRecorder())
+	if !errors.Is(err, ErrNoSources) {
+		t.Errorf("expected ErrNoSources, got %v", err)
+	}
+}
+
+func TestComposite_Clear(t *testing.T) {
+	ctx := context.Background()
+	failed := errors.New("clear failed")
+
+	for name, tc := range map[string]struct {
+		clearErr error
+		calls    []string
+	}{
+		"all clearers":   {calls: []string{"clear:a", "clear:c"}},
+		"stops on error": {clearErr: failed, calls: []string{"clear:a"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var calls []string
+			c := &Composite{Sources: []Source{
+				{HTTPAuthTokenSerializer: &challengingSource{stubSource: &stubSource{name: "a", calls: &calls}, clearErr: tc.clearErr}, Name: "a"},
+				{HTTPAuthTokenSerializer: &stubSource{name: "b", calls: &calls}, Name: "b"},
+				{HTTPAuthTokenSerializer: &challengingSource{stubSource: &stubSource{name: "c", calls: &calls}}, Name: "c"},
+			}}
+
+			err := c.ClearAuthToken(ctx, httptest.NewRecorder(), httptest.NewRequest("POST", "/logout", nil))
+			if !errors.Is(err, tc.clearErr) || !reflect.DeepEqual(calls, tc.calls) {
+				t.Errorf("expected %v with calls %v, got %v with %v", tc.clearErr, tc.calls, err, calls)
+			}
+		})
+	}
+}
+
+// Cookie and Bearer may be used together, for instance for browser and API clients of same endpoints.
+func TestComposite_CookieAndBearer(t *testing.T) {
+	ctx := context.Background()
+	cookie := &Cookie{Serializer: rawSerializer{}}
+	c := &Composite{Sources: []Source{
+		{HTTPAuthTokenSerializer: cookie, Name: "cookie"},
+		{HTTPAuthTokenSerializer: &Bearer{Serializer: rawSerializer{}}, Name: "bearer"},
+	}}
+
+	r := requestWith(setCookies(t, cookie, "cookie token"))
+	at, source, err := c.LoadAuthToken(ctx, r)
+	if err != nil || at != "cookie token" || source != "cookie" {
+		t.Errorf("unexpected result %v from %q, %v", at, source, err)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer bearer token")
+	at, source, err = c.LoadAuthToken(ctx, r)
+	if err != nil || at != "bearer token" || source != "bearer" {
+		t.Errorf("unexpected result %v from %q, %v", at, source, err)
+	}
+}
@@ -0,0 +1,232 @@
+// Package transport implements rocho.HTTPAuthTokenSerializer for common ways of passing tokens over HTTP.
+package transport
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+const (
+	hostPrefix         = "__Host-"
+	chunkMarkerPrefix  = "chunks:"
+	defaultCookieName  = "rocho_token"
+	defaultChunkSize   = 3800
+	maxChunkCount      = 32
+	chunkNameSeparator = "_"
+)
+
+// Cookie implements rocho.HTTPAuthTokenSerializer and rocho.HTTPAuthTokenClearer using cookies.
+// It wraps any rocho.AuthTokenSerializer.
+//
+// Cookies are always HttpOnly. They are Secure and SameSite=Lax unless configured otherwise.
+//
+// Serialized token is stored as is, so wrapped serializer must produce tokens consisting of URL-safe characters only,
+// which is the case for all serializers in rocho.
+//
+// If encoded token does not fit in single cookie, it's split into many cookies.
+// In that case base cookie contains number of chunks and chunks are stored in cookies with "_1", "_2", ... suffixes.
+type Cookie struct {
+	Serializer rocho.AuthTokenSerializer
+
+	Name   string // if empty, "rocho_token" is used
+	Path   string
+	Domain string
+
+	// HostPrefix adds "__Host-" prefix to cookie names.
+	// Browsers accept such cookies only if they are Secure, have path "/" and no domain, so these are enforced.
+	HostPrefix bool
+	// Insecure disables Secure flag. It should be used only for development over plain HTTP.
+	Insecure bool
+	// SameSite defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+
+	// MaxAge is used for tokens, which do not implement rocho.ExpiringAuthToken or never expire.
+	// Zero means session cookie.
+	MaxAge time.Duration
+	// ChunkSize is max size of single cookie value. If zero, 3800 is used.
+	ChunkSize int
+
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+func (c *Cookie) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (c *Cookie) name() string {
+	name := c.Name
+	if name == "" {
+		name = defaultCookieName
+	}
+	if c.HostPrefix {
+		name = hostPrefix + name
+	}
+	return name
+}
+
+func (c *Cookie) chunkName(i int) string {
+	return c.name() + chunkNameSeparator + strconv.Itoa(i)
+}
+
+func (c *Cookie) newCookie(name, value string) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		Secure:   !c.Insecure,
+		HttpOnly: true,
+		SameSite: c.SameSite,
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	if c.HostPrefix {
+		cookie.Secure = true
+		cookie.Path = "/"
+		cookie.Domain = ""
+	}
+	return cookie
+}
+
+func (c *Cookie) maxAge(at rocho.AuthToken) (maxAge int) {
+	if eat, ok := at.(rocho.ExpiringAuthToken); ok {
+		expiresAt := eat.ExpiresAt()
+		if !expiresAt.IsZero() {
+			maxAge = int(expiresAt.Sub(c.now()) / time.Second)
+			if maxAge <= 0 {
+				// token is already expired, so remove cookie
+				maxAge = -1
+			}
+			return
+		}
+	}
+	maxAge = int(c.MaxAge / time.Second)
+	return
+}
+
+// SerializeAuthTokenToResponse serializes AuthToken and sets cookie(s) containing it.
+// Returns ErrCookieTooLarge if token does not fit in 32 chunks.
+func (c *Cookie) SerializeAuthTokenToResponse(ctx context.Context, at rocho.AuthToken, w http.ResponseWriter) (err error) {
+	data, err := c.Serializer.SerializeAuthToken(ctx, at)
+	if err != nil {
+		return
+	}
+	value := string(data)
+	maxAge := c.maxAge(at)
+
+	chunkSize := c.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	if len(value) <= chunkSize {
+		cookie := c.newCookie(c.name(), value)
+		cookie.MaxAge = maxAge
+		http.SetCookie(w, cookie)
+		return
+	}
+
+	chunkCount := (len(value) + chunkSize - 1) / chunkSize
+	if chunkCount > maxChunkCount {
+		err = ErrCookieTooLarge
+		return
+	}
+
+	cookie := c.newCookie(c.name(), chunkMarkerPrefix+strconv.Itoa(chunkCount))
+	cookie.MaxAge = maxAge
+	http.SetCookie(w, cookie)
+
+	for i := 1; i <= chunkCount; i++ {
+		end := i * chunkSize
+		if end > len(value) {
+			end = len(value)
+		}
+		cookie := c.newCookie(c.chunkName(i), value[(i-1)*chunkSize:end])
+		cookie.MaxAge = maxAge
+		http.SetCookie(w, cookie)
+	}
+	return
+}
+
+// DeserializeAuthTokenFromRequest joins cookie(s) from request and deserializes AuthToken from them.
+// Returns rocho.ErrNoAuthToken if there is no cookie.
+func (c *Cookie) DeserializeAuthTokenFromRequest(ctx context.Context, r *http.Request) (at rocho.AuthToken, err error) {
+	base, err := r.Cookie(c.name())
+	if err == http.ErrNoCookie || (err == nil && base.Value == "") {
+		err = rocho.ErrNoAuthToken
+		return
+	} else if err != nil {
+		return
+	}
+
+	value := base.Value
+	chunkCount, chunked, err := parseChunkMarker(value)
+	if err != nil {
+		return
+	}
+	if chunked {
+		var b strings.Builder
+		for i := 1; i <= chunkCount; i++ {
+			var chunk *http.Cookie
+			chunk, err = r.Cookie(c.chunkName(i))
+			if err != nil {
+				err = ErrMalformedCookie
+				return
+			}
+			b.WriteString(chunk.Value)
+		}
+		value = b.String()
+	}
+
+	at, err = c.Serializer.DeserializeAuthToken(ctx, []byte(value))
+	return
+}
+
+// ClearAuthToken removes cookie(s) containing token.
+// If request is given, all chunks present in it are removed as well.
+func (c *Cookie) ClearAuthToken(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+	cookie := c.newCookie(c.name(), "")
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+
+	if r == nil {
+		return
+	}
+	base, cerr := r.Cookie(c.name())
+	if cerr != nil {
+		return
+	}
+	chunkCount, _, cerr := parseChunkMarker(base.Value)
+	if cerr != nil {
+		return
+	}
+	for i := 1; i <= chunkCount; i++ {
+		cookie := c.newCookie(c.chunkName(i), "")
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+	}
+	return
+}
+
+func parseChunkMarker(value string) (chunkCount int, chunked bool, err error) {
+	if !strings.HasPrefix(value, chunkMarkerPrefix) {
+		return
+	}
+	chunked = true
+	chunkCount, err = strconv.Atoi(value[len(chunkMarkerPrefix):])
+	if err != nil || chunkCount <= 0 || chunkCount > maxChunkCount {
+		chunkCount = 0
+		err = ErrMalformedCookie
+	}
+	return
+}
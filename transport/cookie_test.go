@@ -0,0 +1,215 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teawithsand/rocho"
+)
+
+// rawSerializer serializes string AuthTokens as they are.
+type rawSerializer struct{}
+
+func (rawSerializer) SerializeAuthToken(ctx context.Context, at rocho.AuthToken) (data []byte, err error) {
+	switch t := at.(type) {
+	case string:
+		data = []byte(t)
+	case *expiringToken:
+		data = []byte(t.Value)
+	}
+	return
+}
+
+func (rawSerializer) DeserializeAuthToken(ctx context.Context, data []byte) (at rocho.AuthToken, err error) {
+	at = string(data)
+	return
+}
+
+type expiringToken struct {
+	Value string
+	Exp   time.Time
+}
+
+func (et *expiringToken) ExpiresAt() time.Time {
+	return et.Exp
+}
+
+func setCookies(t *testing.T, c *Cookie, at rocho.AuthToken) (cookies []*http.Cookie) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	err := c.SerializeAuthTokenToResponse(context.Background(), at, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies = w.Result().Cookies()
+	return
+}
+
+func requestWith(cookies []*http.Cookie) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, cookie := range cookies {
+		r.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	return r
+}
+
+func TestCookie_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	for name, tc := range map[string]struct {
+		token   string
+		cookies int
+	}{
+		"single": {token: "token", cookies: 1},
+		"exact":  {token: strings.Repeat("a", 10), cookies: 1},
+		"split":  {token: strings.Repeat("a", 10) + strings.Repeat("b", 10) + "c", cookies: 4},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := &Cookie{Serializer: rawSerializer{}, ChunkSize: 10}
+			cookies := setCookies(t, c, tc.token)
+			if len(cookies) != tc.cookies {
+				t.Fatalf("expected %d cookies, got %d", tc.cookies, len(cookies))
+			}
+			for _, cookie := range cookies {
+				if len(cookie.Value) > 10 || !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+					t.Errorf("unexpected cookie %+v", cookie)
+				}
+			}
+
+			// order of cookies in request does not matter
+			reversed := make([]*http.Cookie, 0, len(cookies))
+			for i := len(cookies) - 1; i >= 0; i-- {
+				reversed = append(reversed, cookies[i])
+			}
+			at, err := c.DeserializeAuthTokenFromRequest(ctx, requestWith(reversed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if at != tc.token {
+				t.Errorf("expected %q, got %q", tc.token, at)
+			}
+		})
+	}
+}
+
+func TestCookie_MalformedChunks(t *testing.T) {
+	ctx := context.Background()
+	c := &Cookie{Serializer: rawSerializer{}, ChunkSize: 10}
+	cookies := setCookies(t, c, strings.Repeat("a", 25))
+
+	for name, modify := range map[string]func(cookies []*http.Cookie) []*http.Cookie{
+		"missing chunk": func(cookies []*http.Cookie) []*http.Cookie {
+			return append(cookies[:2:2], cookies[3:]...)
+		},
+		"bad marker": func(cookies []*http.Cookie) []*http.Cookie {
+			return []*http.Cookie{{Name: c.name(), Value: chunkMarkerPrefix + "x"}}
+		},
+		"too many chunks": func(cookies []*http.Cookie) []*http.Cookie {
+			return []*http.Cookie{{Name: c.name(), Value: chunkMarkerPrefix + "33"}}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := c.DeserializeAuthTokenFromRequest(ctx, requestWith(modify(cookies)))
+			if !errors.Is(err, ErrMalformedCookie) {
+				t.Errorf("expected ErrMalformedCookie, got %v", err)
+			}
+		})
+	}
+
+	_, err := c.DeserializeAuthTokenFromRequest(ctx, requestWith(nil))
+	if !errors.Is(err, rocho.ErrNoAuthToken) {
+		t.Errorf("expected ErrNoAuthToken, got %v", err)
+	}
+}
+
+func TestCookie_TooLarge(t *testing.T) {
+	c := &Cookie{Serializer: rawSerializer{}, ChunkSize: 10}
+	err := c.SerializeAuthTokenToResponse(context.Background(), strings.Repeat("a", 10*maxChunkCount+1), httptest.NewRecorder())
+	if !errors.Is(err, ErrCookieTooLarge) {
+		t.Errorf("expected ErrCookieTooLarge, got %v", err)
+	}
+}
+
+func TestCookie_ClearRemovesChunks(t *testing.T) {
+	c := &Cookie{Serializer: rawSerializer{}, ChunkSize: 10}
+	r := requestWith(setCookies(t, c, strings.Repeat("a", 25)))
+
+	w := httptest.NewRecorder()
+	err := c.ClearAuthToken(context.Background(), w, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleared := map[string]bool{}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.MaxAge >= 0 || cookie.Value != "" {
+			t.Errorf("expected cookie to be removed, got %+v", cookie)
+		}
+		cleared[cookie.Name] = true
+	}
+	for _, name := range []string{c.name(), c.chunkName(1), c.chunkName(2), c.chunkName(3)} {
+		if !cleared[name] {
+			t.Errorf("expected %s to be cleared", name)
+		}
+	}
+}
+
+func TestCookie_MaxAge(t *testing.T) {
+	now := time.Now()
+	c := &Cookie{
+		Serializer: rawSerializer{},
+		MaxAge:     time.Hour,
+		Now: func() time.Time {
+			return now
+		},
+	}
+
+	for name, tc := range map[string]struct {
+		at     rocho.AuthToken
+		maxAge int
+	}{
+		"non expiring":   {at: "token", maxAge: 3600},
+		"never expiring": {at: &expiringToken{Value: "token"}, maxAge: 3600},
+		"expiring":       {at: &expiringToken{Value: "token", Exp: now.Add(time.Minute)}, maxAge: 60},
+		"expired":        {at: &expiringToken{Value: "token", Exp: now.Add(-time.Minute)}, maxAge: -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cookies := setCookies(t, c, tc.at)
+			if len(cookies) != 1 || cookies[0].MaxAge != tc.maxAge {
+				t.Errorf("expected max age %d, got %+v", tc.maxAge, cookies)
+			}
+		})
+	}
+}
+
+func TestCookie_HostPrefix(t *testing.T) {
+	c := &Cookie{
+		Serializer: rawSerializer{},
+		Name:       "token",
+		HostPrefix: true,
+		Insecure:   true,
+		Path:       "/app",
+		Domain:     "example.com",
+		ChunkSize:  10,
+	}
+
+	cookies := setCookies(t, c, strings.Repeat("a", 15))
+	if len(cookies) != 3 {
+		t.Fatalf("expected 3 cookies, got %d", len(cookies))
+	}
+	for _, cookie := range cookies {
+		if !strings.HasPrefix(cookie.Name, "__Host-token") || !cookie.Secure || cookie.Path != "/" || cookie.Domain != "" {
+			t.Errorf("cookie violates __Host- prefix constraints: %+v", cookie)
+		}
+	}
+
+	at, err := c.DeserializeAuthTokenFromRequest(context.Background(), requestWith(cookies))
+	if err != nil || at != strings.Repeat("a", 15) {
+		t.Errorf("unexpected result %q, %v", at, err)
+	}
+}
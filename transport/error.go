@@ -0,0 +1,55 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/teawithsand/rocho"
+)
+
+// ErrMalformedCookie is returned when cookie containing token has invalid format or some of it's chunks are missing.
+var ErrMalformedCookie = errors.New("rocho/transport: Malformed cookie")
+
+// ErrCookieTooLarge is returned when serialized token does not fit in maximal number of cookie chunks.
+var ErrCookieTooLarge = errors.New("rocho/transport: Token is too large to be stored in cookies")
+
+// ErrMultipleTokens is returned when request uses more than one method of passing bearer token, which RFC 6750 forbids.
+// It matches rocho.ErrInvalidAuthRequest, so it's answered with 400 as RFC 6750 section 3.1 requires.
+var ErrMultipleTokens error = invalidRequestError("rocho/transport: Request contains token passed using more than one method")
+
+// invalidRequestError is error matching rocho.ErrInvalidAuthRequest.
+type invalidRequestError string
+
+func (err invalidRequestError) Error() string {
+	return string(err)
+}
+
+// Is makes errors.Is(err, rocho.ErrInvalidAuthRequest) work.
+func (err invalidRequestError) Is(target error) bool {
+	return target == rocho.ErrInvalidAuthRequest
+}
+
+// ErrNoSources is returned when Composite has no sources configured.
+var ErrNoSources = errors.New("rocho/transport: Composite has no sources")
+
+// SourceError is returned by Composite when source contains token, but it's invalid.
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+func (err *SourceError) Error() string {
+	if err == nil {
+		return "<nil>"
+	}
+	if err.Err == nil {
+		return fmt.Sprintf("rocho/transport: Invalid token in source %q", err.Source)
+	}
+	return fmt.Sprintf("rocho/transport: Invalid token in source %q: %s", err.Source, err.Err.Error())
+}
+func (err *SourceError) Unwrap() error {
+	if err == nil {
+		return nil
+	}
+	return err.Err
+}
@@ -0,0 +1,205 @@
+package rocho
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrAuthTokenExpired is returned by ExpirationValidator when AuthToken has expired.
+var ErrAuthTokenExpired = errors.New("rocho: AuthToken has expired")
+
+// ErrAuthTokenNotValidYet is returned by NotBeforeValidator when AuthToken is not valid yet.
+var ErrAuthTokenNotValidYet = errors.New("rocho: AuthToken is not valid yet")
+
+// ErrInvalidIssuer is returned by IssuerValidator when AuthToken was issued by unknown issuer.
+var ErrInvalidIssuer = errors.New("rocho: AuthToken has invalid issuer")
+
+// ErrInvalidAudience is returned by AudienceValidator when AuthToken is not intended for this audience.
+var ErrInvalidAudience = errors.New("rocho: AuthToken has invalid audience")
+
+// ErrMissingClaim is returned by validators in required mode when AuthToken does not contain value they check.
+var ErrMissingClaim = errors.New("rocho: AuthToken does not contain required claim")
+
+func now(f func() time.Time) time.Time {
+	if f != nil {
+		return f()
+	}
+	return time.Now()
+}
+
+// ExpirationValidator rejects AuthTokens implementing ExpiringAuthToken, which have expired.
+//
+// By default it validates AuthToken before refill. Set AfterRefill to validate refilled one instead.
+type ExpirationValidator struct {
+	// Leeway is clock skew tolerated.
+	Leeway time.Duration
+	// Required makes validator reject AuthTokens, which do not have expiration time.
+	Required    bool
+	AfterRefill bool
+
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+func (v *ExpirationValidator) validate(at AuthToken) (err error) {
+	var expiresAt time.Time
+	if eat, ok := at.(ExpiringAuthToken); ok {
+		expiresAt = eat.ExpiresAt()
+	}
+
+	if expiresAt.IsZero() {
+		if v.Required {
+			err = ErrMissingClaim
+		}
+		return
+	}
+	if !now(v.Now).Add(-v.Leeway).Before(expiresAt) {
+		err = ErrAuthTokenExpired
+	}
+	return
+}
+
+func (v *ExpirationValidator) ValidatePreRefill(ctx context.Context, at AuthToken) (err error) {
+	if !v.AfterRefill {
+		err = v.validate(at)
+	}
+	return
+}
+
+func (v *ExpirationValidator) ValidateAfterRefill(ctx context.Context, at AuthToken) (err error) {
+	if v.AfterRefill {
+		err = v.validate(at)
+	}
+	return
+}
+
+// NotBeforeValidator rejects AuthTokens implementing NotBeforeAuthToken, which are not valid yet.
+//
+// By default it validates AuthToken before refill. Set AfterRefill to validate refilled one instead.
+type NotBeforeValidator struct {
+	// Leeway is clock skew tolerated.
+	Leeway      time.Duration
+	AfterRefill bool
+
+	// Now returns current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+func (v *NotBeforeValidator) validate(at AuthToken) (err error) {
+	nbat, ok := at.(NotBeforeAuthToken)
+	if !ok {
+		return
+	}
+	notBefore := nbat.NotBefore()
+	if !notBefore.IsZero() && now(v.Now).Add(v.Leeway).Before(notBefore) {
+		err = ErrAuthTokenNotValidYet
+	}
+	return
+}
+
+func (v *NotBeforeValidator) ValidatePreRefill(ctx context.Context, at AuthToken) (err error) {
+	if !v.AfterRefill {
+		err = v.validate(at)
+	}
+	return
+}
+
+func (v *NotBeforeValidator) ValidateAfterRefill(ctx context.Context, at AuthToken) (err error) {
+	if v.AfterRefill {
+		err = v.validate(at)
+	}
+	return
+}
+
+// IssuerValidator requires AuthTokens to be issued by one of given issuers.
+// AuthTokens, which do not implement IssuerAuthToken or have no issuer, are rejected only if Required is set.
+//
+// By default it validates AuthToken before refill. Set AfterRefill to validate refilled one instead.
+type IssuerValidator struct {
+	Issuers     []string
+	Required    bool
+	AfterRefill bool
+}
+
+func (v *IssuerValidator) validate(at AuthToken) (err error) {
+	var issuer string
+	if iat, ok := at.(IssuerAuthToken); ok {
+		issuer = iat.Issuer()
+	}
+
+	if issuer == "" {
+		if v.Required {
+			err = ErrMissingClaim
+		}
+		return
+	}
+	for _, expected := range v.Issuers {
+		if issuer == expected {
+			return
+		}
+	}
+	err = ErrInvalidIssuer
+	return
+}
+
+func (v *IssuerValidator) ValidatePreRefill(ctx context.Context, at AuthToken) (err error) {
+	if !v.AfterRefill {
+		err = v.validate(at)
+	}
+	return
+}
+
+func (v *IssuerValidator) ValidateAfterRefill(ctx context.Context, at AuthToken) (err error) {
+	if v.AfterRefill {
+		err = v.validate(at)
+	}
+	return
+}
+
+// AudienceValidator requires AuthTokens to be intended for at least one of given audiences.
+// AuthTokens, which do not implement AudienceAuthToken or have no audience, are rejected only if Required is set.
+//
+// By default it validates AuthToken before refill. Set AfterRefill to validate refilled one instead.
+type AudienceValidator struct {
+	Audience    []string
+	Required    bool
+	AfterRefill bool
+}
+
+func (v *AudienceValidator) validate(at AuthToken) (err error) {
+	var audience []string
+	if aat, ok := at.(AudienceAuthToken); ok {
+		audience = aat.Audience()
+	}
+
+	if len(audience) == 0 {
+		if v.Required {
+			err = ErrMissingClaim
+		}
+		return
+	}
+	for _, aud := range audience {
+		for _, expected := range v.Audience {
+			if aud == expected {
+				return
+			}
+		}
+	}
+	err = ErrInvalidAudience
+	return
+}
+
+func (v *AudienceValidator) ValidatePreRefill(ctx context.Context, at AuthToken) (err error) {
+	if !v.AfterRefill {
+		err = v.validate(at)
+	}
+	return
+}
+
+func (v *AudienceValidator) ValidateAfterRefill(ctx context.Context, at AuthToken) (err error) {
+	if v.AfterRefill {
+		err = v.validate(at)
+	}
+	return
+}
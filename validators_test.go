@@ -0,0 +1,235 @@
+package rocho
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// claimsToken implements all AuthToken interfaces checked by validators.
+type claimsToken struct {
+	exp, nbf time.Time
+	iss      string
+	aud      []string
+}
+
+func (ct *claimsToken) ExpiresAt() time.Time {
+	return ct.exp
+}
+
+func (ct *claimsToken) NotBefore() time.Time {
+	return ct.nbf
+}
+
+func (ct *claimsToken) Issuer() string {
+	return ct.iss
+}
+
+func (ct *claimsToken) Audience() []string {
+	return ct.aud
+}
+
+type validatorTestCase struct {
+	validator AuthTokenValidator
+	at        AuthToken
+	err       error
+}
+
+// runValidatorTests checks that validators return expected errors before refill and nothing after refill.
+func runValidatorTests(t *testing.T, cases map[string]validatorTestCase) {
+	t.Helper()
+	ctx := context.Background()
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.validator.ValidatePreRefill(ctx, tc.at)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+			err = tc.validator.ValidateAfterRefill(ctx, tc.at)
+			if err != nil {
+				t.Errorf("expected no validation after refill, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExpirationValidator(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time {
+		return now
+	}
+
+	runValidatorTests(t, map[string]validatorTestCase{
+		"valid": {
+			validator: &ExpirationValidator{Now: clock},
+			at:        &claimsToken{exp: now.Add(time.Second)},
+		},
+		"expired": {
+			validator: &ExpirationValidator{Now: clock},
+			at:        &claimsToken{exp: now.Add(-time.Second)},
+			err:       ErrAuthTokenExpired,
+		},
+		"expiring now": {
+			validator: &ExpirationValidator{Now: clock},
+			at:        &claimsToken{exp: now},
+			err:       ErrAuthTokenExpired,
+		},
+		"expired within leeway": {
+			validator: &ExpirationValidator{Now: clock, Leeway: time.Minute},
+			at:        &claimsToken{exp: now.Add(-time.Minute + time.Second)},
+		},
+		"expired beyond leeway": {
+			validator: &ExpirationValidator{Now: clock, Leeway: time.Minute},
+			at:        &claimsToken{exp: now.Add(-time.Minute)},
+			err:       ErrAuthTokenExpired,
+		},
+		"no expiration": {
+			validator: &ExpirationValidator{Now: clock},
+			at:        &claimsToken{},
+		},
+		"not expiring token": {
+			validator: &ExpirationValidator{Now: clock},
+			at:        "token",
+		},
+		"no expiration when required": {
+			validator: &ExpirationValidator{Now: clock, Required: true},
+			at:        &claimsToken{},
+			err:       ErrMissingClaim,
+		},
+		"not expiring token when required": {
+			validator: &ExpirationValidator{Now: clock, Required: true},
+			at:        "token",
+			err:       ErrMissingClaim,
+		},
+	})
+}
+
+func TestNotBeforeValidator(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time {
+		return now
+	}
+
+	runValidatorTests(t, map[string]validatorTestCase{
+		"valid": {
+			validator: &NotBeforeValidator{Now: clock},
+			at:        &claimsToken{nbf: now},
+		},
+		"not valid yet": {
+			validator: &NotBeforeValidator{Now: clock},
+			at:        &claimsToken{nbf: now.Add(time.Second)},
+			err:       ErrAuthTokenNotValidYet,
+		},
+		"within leeway": {
+			validator: &NotBeforeValidator{Now: clock, Leeway: time.Minute},
+			at:        &claimsToken{nbf: now.Add(time.Minute)},
+		},
+		"beyond leeway": {
+			validator: &NotBeforeValidator{Now: clock, Leeway: time.Minute},
+			at:        &claimsToken{nbf: now.Add(time.Minute + time.Second)},
+			err:       ErrAuthTokenNotValidYet,
+		},
+		"no not before": {
+			validator: &NotBeforeValidator{Now: clock},
+			at:        &claimsToken{},
+		},
+		"other token": {
+			validator: &NotBeforeValidator{Now: clock},
+			at:        "token",
+		},
+	})
+}
+
+func TestIssuerValidator(t *testing.T) {
+	issuers := []string{"first", "second"}
+	runValidatorTests(t, map[string]validatorTestCase{
+		"valid": {
+			validator: &IssuerValidator{Issuers: issuers},
+			at:        &claimsToken{iss: "second"},
+		},
+		"invalid": {
+			validator: &IssuerValidator{Issuers: issuers},
+			at:        &claimsToken{iss: "third"},
+			err:       ErrInvalidIssuer,
+		},
+		"no issuer": {
+			validator: &IssuerValidator{Issuers: issuers},
+			at:        &claimsToken{},
+		},
+		"no issuer when required": {
+			validator: &IssuerValidator{Issuers: issuers, Required: true},
+			at:        &claimsToken{},
+			err:       ErrMissingClaim,
+		},
+		"other token when required": {
+			validator: &IssuerValidator{Issuers: issuers, Required: true},
+			at:        "token",
+			err:       ErrMissingClaim,
+		},
+	})
+}
+
+func TestAudienceValidator(t *testing.T) {
+	audience := []string{"api", "admin"}
+	runValidatorTests(t, map[string]validatorTestCase{
+		"valid": {
+			validator: &AudienceValidator{Audience: audience},
+			at:        &claimsToken{aud: []string{"other", "admin"}},
+		},
+		"invalid": {
+			validator: &AudienceValidator{Audience: audience},
+			at:        &claimsToken{aud: []string{"other"}},
+			err:       ErrInvalidAudience,
+		},
+		"no audience": {
+			validator: &AudienceValidator{Audience: audience},
+			at:        &claimsToken{},
+		},
+		"no audience when required": {
+			validator: &AudienceValidator{Audience: audience, Required: true},
+			at:        &claimsToken{},
+			err:       ErrMissingClaim,
+		},
+		"other token when required": {
+			validator: &AudienceValidator{Audience: audience, Required: true},
+			at:        "token",
+			err:       ErrMissingClaim,
+		},
+	})
+}
+
+func TestValidators_AfterRefill(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	clock := func() time.Time {
+		return now
+	}
+	at := &claimsToken{
+		exp: now.Add(-time.Second),
+		nbf: now.Add(time.Second),
+		iss: "other",
+		aud: []string{"other"},
+	}
+
+	for name, tc := range map[string]struct {
+		validator AuthTokenValidator
+		err       error
+	}{
+		"expiration": {validator: &ExpirationValidator{Now: clock, AfterRefill: true}, err: ErrAuthTokenExpired},
+		"not before": {validator: &NotBeforeValidator{Now: clock, AfterRefill: true}, err: ErrAuthTokenNotValidYet},
+		"issuer":     {validator: &IssuerValidator{Issuers: []string{"issuer"}, AfterRefill: true}, err: ErrInvalidIssuer},
+		"audience":   {validator: &AudienceValidator{Audience: []string{"api"}, AfterRefill: true}, err: ErrInvalidAudience},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := tc.validator.ValidatePreRefill(ctx, at)
+			if err != nil {
+				t.Errorf("expected no validation before refill, got %v", err)
+			}
+			err = tc.validator.ValidateAfterRefill(ctx, at)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}